@@ -2,33 +2,70 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"time"
 
+	brokermemory "github.com/AlexMickh/speak-chat/internal/broker/memory"
+	"github.com/AlexMickh/speak-chat/internal/broker/nats"
 	"github.com/AlexMickh/speak-chat/internal/config"
 	authclient "github.com/AlexMickh/speak-chat/internal/grpc/clients/auth"
 	"github.com/AlexMickh/speak-chat/internal/grpc/server"
 	"github.com/AlexMickh/speak-chat/internal/service"
+	"github.com/AlexMickh/speak-chat/internal/storage/filesystem"
+	"github.com/AlexMickh/speak-chat/internal/storage/memory"
 	"github.com/AlexMickh/speak-chat/internal/storage/minio"
 	"github.com/AlexMickh/speak-chat/internal/storage/postgres"
 	"github.com/AlexMickh/speak-chat/internal/storage/redis"
+	"github.com/AlexMickh/speak-chat/internal/worker/audit"
+	"github.com/AlexMickh/speak-chat/internal/worker/gc"
+	"github.com/AlexMickh/speak-chat/internal/worker/outbox"
+	"github.com/AlexMickh/speak-chat/internal/worker/purge"
+	"github.com/AlexMickh/speak-chat/internal/worker/webhook"
 	"github.com/AlexMickh/speak-chat/pkg/logger"
 	minioclient "github.com/AlexMickh/speak-chat/pkg/minio-client"
+	natsclient "github.com/AlexMickh/speak-chat/pkg/nats-client"
 	postgresclient "github.com/AlexMickh/speak-chat/pkg/postgres-client"
 	redisclient "github.com/AlexMickh/speak-chat/pkg/redis-client"
 	"github.com/AlexMickh/speak-protos/pkg/api/chat"
 	"github.com/jackc/pgx/v5/pgxpool"
+	natslib "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	redislib "github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
 
+const (
+	backendMinio  = "minio"
+	backendLocal  = "local"
+	backendNats   = "nats"
+	backendMemory = "memory"
+)
+
+// blobStorage is implemented by every avatar storage backend.
+type blobStorage interface {
+	service.S3
+	gc.Objects
+}
+
 type App struct {
-	cfg        *config.Config
-	db         *pgxpool.Pool
-	cash       *redislib.Client
-	server     *grpc.Server
-	authClient *authclient.AuthClient
+	cfg         *config.Config
+	db          *pgxpool.Pool
+	cash        *redislib.Client
+	server      *grpc.Server
+	authClient  *authclient.AuthClient
+	gc          *gc.GC
+	relay       *outbox.Relay
+	purger      *purge.Purger
+	partitioner *audit.Partitioner
+	dispatcher  *webhook.Dispatcher
+	localCash   *memory.Memory
+	blobServer  *http.Server
+	natsConn    *natslib.Conn
+	stopWorkers context.CancelFunc
 }
 
 func Register(ctx context.Context, cfg *config.Config) *App {
@@ -54,20 +91,55 @@ func Register(ctx context.Context, cfg *config.Config) *App {
 
 	postgres := postgres.New(db)
 
-	logger.GetFromCtx(ctx).Info(ctx, "initing minio")
-	minioCfg := minioclient.NewConfig(
-		cfg.S3.Endpoint,
-		cfg.S3.User,
-		cfg.S3.Password,
-		cfg.S3.BucketName,
-		cfg.S3.IsUseSsl,
-	)
-	s3, err := minioclient.New(ctx, minioCfg)
-	if err != nil {
-		logger.GetFromCtx(ctx).Fatal(ctx, "failed to init minio", zap.Error(err))
-	}
+	var blobs blobStorage
+	var blobServer *http.Server
+	switch cfg.S3.Backend {
+	case backendLocal:
+		logger.GetFromCtx(ctx).Info(ctx, "initing local blob storage")
+		if cfg.S3.LocalSecret == "" {
+			logger.GetFromCtx(ctx).Fatal(ctx, "local secret is required for local blob storage")
+		}
+		fs, err := filesystem.New(cfg.S3.LocalDir, cfg.S3.LocalBaseUrl, cfg.S3.LocalSecret, cfg.S3.Expires)
+		if err != nil {
+			logger.GetFromCtx(ctx).Fatal(ctx, "failed to init local blob storage", zap.Error(err))
+		}
 
-	minio := minio.New(s3, cfg.S3.BucketName, cfg.S3.Expires)
+		blobServer = &http.Server{
+			Addr:              cfg.S3.LocalAddr,
+			Handler:           fs.Handler(),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		blobs = fs
+	case backendMinio:
+		logger.GetFromCtx(ctx).Info(ctx, "initing minio")
+		if cfg.S3.URLMode == minio.URLModePublic && cfg.S3.PublicBaseUrl == "" {
+			logger.GetFromCtx(ctx).Fatal(ctx, "public base url is required in public url mode")
+		}
+		minioCfg := minioclient.NewConfig(
+			cfg.S3.Endpoint,
+			cfg.S3.User,
+			cfg.S3.Password,
+			cfg.S3.BucketName,
+			cfg.S3.IsUseSsl,
+		)
+		s3, err := minioclient.New(ctx, minioCfg)
+		if err != nil {
+			logger.GetFromCtx(ctx).Fatal(ctx, "failed to init minio", zap.Error(err))
+		}
+
+		blobs, err = minio.New(
+			s3,
+			cfg.S3.BucketName,
+			cfg.S3.Expires,
+			cfg.S3.URLMode,
+			cfg.S3.PublicBaseUrl,
+		)
+		if err != nil {
+			logger.GetFromCtx(ctx).Fatal(ctx, "failed to init minio storage", zap.Error(err))
+		}
+	default:
+		logger.GetFromCtx(ctx).Fatal(ctx, "unknown blob storage backend", zap.String("backend", cfg.S3.Backend))
+	}
 
 	logger.GetFromCtx(ctx).Info(ctx, "initing redis")
 	redisCfg := redisclient.NewConfig(
@@ -81,10 +153,93 @@ func Register(ctx context.Context, cfg *config.Config) *App {
 		logger.GetFromCtx(ctx).Fatal(ctx, "failed to init redis", zap.Error(err))
 	}
 
-	redis := redis.New(cash, cfg.Redis.DB, cfg.Redis.Expiration)
+	redis := redis.New(
+		cash,
+		cfg.Redis.DB,
+		cfg.Redis.Expiration,
+		cfg.Redis.NegativeExpiration,
+		cfg.Redis.EarlyRefreshDelta,
+	)
+
+	var chatCash service.Cash = redis
+	var localCash *memory.Memory
+	if cfg.Redis.LocalCacheSize > 0 {
+		logger.GetFromCtx(ctx).Info(ctx, "initing local cache")
+		localCash = memory.New(redis, redis, cfg.Redis.LocalCacheSize, cfg.Redis.LocalCacheTTL)
+		chatCash = localCash
+	}
+
+	logger.GetFromCtx(ctx).Info(ctx, "initing webhook dispatcher")
+	dispatcher := webhook.New(
+		postgres,
+		webhook.NewClient(cfg.Webhook.Timeout),
+		cfg.Webhook.BatchSize,
+		cfg.Webhook.Lease,
+		cfg.Webhook.MaxAttempts,
+		cfg.Webhook.MaxBackoff,
+	)
 
 	logger.GetFromCtx(ctx).Info(ctx, "initing serice layer")
-	service := service.New(postgres, redis, minio)
+	service := service.New(postgres, chatCash, blobs, dispatcher, cfg.Purge.RestoreWindow)
+
+	logger.GetFromCtx(ctx).Info(ctx, "initing avatar gc")
+	avatarGC := gc.New(blobs, postgres, cfg.GC.GracePeriod, cfg.GC.DryRun)
+
+	publishers := []outbox.Publisher{dispatcher}
+	var natsConn *natslib.Conn
+	switch cfg.Events.Backend {
+	case backendNats:
+		logger.GetFromCtx(ctx).Info(ctx, "initing nats publisher")
+		natsCfg := natsclient.NewConfig(
+			cfg.Events.NatsAddr,
+			cfg.Events.NatsUser,
+			cfg.Events.NatsPassword,
+			cfg.Events.NatsToken,
+			cfg.Events.NatsCredsFile,
+			cfg.Events.NatsTLSCA,
+			cfg.Events.NatsTLSCert,
+			cfg.Events.NatsTLSKey,
+			cfg.Events.Timeout,
+		)
+		natsConn, err = natsclient.New(natsCfg)
+		if err != nil {
+			logger.GetFromCtx(ctx).Fatal(ctx, "failed to init nats", zap.Error(err))
+		}
+
+		js, err := jetstream.New(natsConn)
+		if err != nil {
+			logger.GetFromCtx(ctx).Fatal(ctx, "failed to init jetstream", zap.Error(err))
+		}
+
+		broker := nats.New(js, cfg.Events.SubjectPrefix, cfg.Events.Timeout)
+		publishers = append(publishers, broker)
+	case backendMemory:
+		logger.GetFromCtx(ctx).Info(ctx, "initing in-memory publisher")
+		publishers = append(publishers, brokermemory.New())
+	case "":
+		logger.GetFromCtx(ctx).Info(ctx, "events are disabled")
+	default:
+		logger.GetFromCtx(ctx).Fatal(ctx, "unknown events backend", zap.String("backend", cfg.Events.Backend))
+	}
+
+	logger.GetFromCtx(ctx).Info(ctx, "initing outbox relay")
+	relay := outbox.New(
+		postgres,
+		chatCash,
+		publishers,
+		cfg.Outbox.BatchSize,
+		cfg.Outbox.Lease,
+		cfg.Outbox.MaxBackoff,
+	)
+
+	logger.GetFromCtx(ctx).Info(ctx, "initing purge worker")
+	purger, err := purge.New(postgres, cfg.Purge.RestoreWindow, cfg.Purge.BatchSize)
+	if err != nil {
+		logger.GetFromCtx(ctx).Fatal(ctx, "failed to init purge worker", zap.Error(err))
+	}
+
+	logger.GetFromCtx(ctx).Info(ctx, "initing audit partitioner")
+	partitioner := audit.New(postgres, cfg.Audit.PartitionsAhead)
 
 	logger.GetFromCtx(ctx).Info(ctx, "initing auth client")
 	authClient, err := authclient.New(cfg.AuthServiceAddr)
@@ -98,11 +253,19 @@ func Register(ctx context.Context, cfg *config.Config) *App {
 	chat.RegisterChatServer(server, srv)
 
 	return &App{
-		cfg:        cfg,
-		db:         db,
-		cash:       cash,
-		server:     server,
-		authClient: authClient,
+		cfg:         cfg,
+		db:          db,
+		cash:        cash,
+		server:      server,
+		authClient:  authClient,
+		gc:          avatarGC,
+		relay:       relay,
+		purger:      purger,
+		partitioner: partitioner,
+		dispatcher:  dispatcher,
+		localCash:   localCash,
+		blobServer:  blobServer,
+		natsConn:    natsConn,
 	}
 }
 
@@ -125,6 +288,47 @@ func (a *App) Run(ctx context.Context) {
 	}()
 
 	logger.GetFromCtx(ctx).Info(ctx, "server started", zap.Int("port", a.cfg.Port))
+
+	if a.blobServer != nil {
+		go func() {
+			err := a.blobServer.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.GetFromCtx(ctx).Fatal(ctx, "failed to serve blobs", zap.Error(err))
+			}
+		}()
+
+		logger.GetFromCtx(ctx).Info(ctx, "blob server started", zap.String("addr", a.blobServer.Addr))
+	}
+
+	// workers outlive the startup context, so only its values are kept
+	workersCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	a.stopWorkers = cancel
+
+	if a.localCash != nil {
+		go a.localCash.Start(workersCtx)
+		logger.GetFromCtx(ctx).Info(ctx, "local cache invalidation started")
+	}
+
+	go a.relay.Start(workersCtx, a.cfg.Outbox.Interval)
+	logger.GetFromCtx(ctx).Info(ctx, "outbox relay started", zap.Duration("interval", a.cfg.Outbox.Interval))
+
+	go a.partitioner.Start(workersCtx, a.cfg.Audit.PartitionInterval)
+	logger.GetFromCtx(ctx).Info(ctx, "audit partitioner started", zap.Duration("interval", a.cfg.Audit.PartitionInterval))
+
+	if a.cfg.GC.Enabled {
+		go a.gc.Start(workersCtx, a.cfg.GC.Interval)
+		logger.GetFromCtx(ctx).Info(ctx, "avatar gc started", zap.Duration("interval", a.cfg.GC.Interval))
+	}
+
+	if a.cfg.Webhook.Enabled {
+		go a.dispatcher.Start(workersCtx, a.cfg.Webhook.Interval)
+		logger.GetFromCtx(ctx).Info(ctx, "webhook dispatcher started", zap.Duration("interval", a.cfg.Webhook.Interval))
+	}
+
+	if a.cfg.Purge.Enabled {
+		go a.purger.Start(workersCtx, a.cfg.Purge.Interval)
+		logger.GetFromCtx(ctx).Info(ctx, "purge worker started", zap.Duration("interval", a.cfg.Purge.Interval))
+	}
 }
 
 func (a *App) GracefulStop(ctx context.Context) {
@@ -132,6 +336,19 @@ func (a *App) GracefulStop(ctx context.Context) {
 
 	ctx = logger.GetFromCtx(ctx).With(ctx, zap.String("op", op))
 
+	logger.GetFromCtx(ctx).Info(ctx, "stopping workers")
+	if a.stopWorkers != nil {
+		a.stopWorkers()
+	}
+
+	if a.natsConn != nil {
+		logger.GetFromCtx(ctx).Info(ctx, "stopping nats")
+		err := a.natsConn.Drain()
+		if err != nil {
+			logger.GetFromCtx(ctx).Error(ctx, "failed to stop nats", zap.Error(err))
+		}
+	}
+
 	logger.GetFromCtx(ctx).Info(ctx, "stopping postgres")
 	a.db.Close()
 
@@ -144,6 +361,16 @@ func (a *App) GracefulStop(ctx context.Context) {
 	logger.GetFromCtx(ctx).Info(ctx, "stopping auth client")
 	a.authClient.Close()
 
+	if a.blobServer != nil {
+		logger.GetFromCtx(ctx).Info(ctx, "stopping blob server")
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		err = a.blobServer.Shutdown(shutdownCtx)
+		cancel()
+		if err != nil {
+			logger.GetFromCtx(ctx).Error(ctx, "failed to stop blob server", zap.Error(err))
+		}
+	}
+
 	logger.GetFromCtx(ctx).Info(ctx, "stopping server")
 	a.server.GracefulStop()
 }
@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+)
+
+// Memory keeps published events in process. It is meant for tests
+// and local runs without a broker.
+type Memory struct {
+	mu     sync.Mutex
+	events []models.Event
+}
+
+func New() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Publish(ctx context.Context, event models.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, event)
+
+	return nil
+}
+
+// Events returns copy of all published events in publication order.
+func (m *Memory) Events() []models.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.events)
+}
@@ -0,0 +1,166 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/pkg/api/events"
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type JetStream interface {
+	Publish(ctx context.Context, subject string, payload []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// NATS publishes events to JetStream. Publish succeeds only when a stream
+// has acknowledged the message, and message id lets the stream drop
+// events the outbox relay delivers again.
+type NATS struct {
+	js      JetStream
+	prefix  string
+	timeout time.Duration
+}
+
+func New(js JetStream, prefix string, timeout time.Duration) *NATS {
+	return &NATS{
+		js:      js,
+		prefix:  prefix,
+		timeout: timeout,
+	}
+}
+
+// Publish sends event encoded as events.Event protobuf to subject
+// "<prefix>.<event type>" and waits for the stream acknowledgement.
+func (n *NATS) Publish(ctx context.Context, event models.Event) error {
+	const op = "broker.nats.Publish"
+
+	payload, err := marshal(event)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	_, err = n.js.Publish(ctx, n.subject(event.Type), payload, jetstream.WithMsgID(event.ID))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (n *NATS) subject(eventType string) string {
+	if n.prefix == "" {
+		return eventType
+	}
+
+	return n.prefix + "." + eventType
+}
+
+// eventData is outbox payload of every event kind.
+type eventData struct {
+	UserId        string    `json:"user_id"`
+	ParticipantId string    `json:"participant_id"`
+	RequestId     string    `json:"request_id"`
+	RequesterId   string    `json:"requester_id"`
+	BannedId      string    `json:"banned_id"`
+	UnbannedId    string    `json:"unbanned_id"`
+	MutedId       string    `json:"muted_id"`
+	UnmutedId     string    `json:"unmuted_id"`
+	Reason        string    `json:"reason"`
+	Until         time.Time `json:"until"`
+}
+
+func marshal(event models.Event) ([]byte, error) {
+	var data eventData
+	if len(event.Data) > 0 {
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := &events.Event{
+		Id:         event.ID,
+		Type:       event.Type,
+		Version:    uint32(event.Version),
+		ChatId:     event.ChatId,
+		OccurredAt: timestamppb.New(event.OccurredAt),
+	}
+	if event.Chat != nil {
+		msg.Chat = &events.Chat{
+			Id:               event.Chat.ID,
+			Name:             event.Chat.Name,
+			Description:      event.Chat.Description,
+			AvatarHash:       event.Chat.AvatarHash,
+			OwnerId:          event.Chat.OwnerId,
+			ParticipantsId:   event.Chat.ParticipantsId,
+			Version:          event.Chat.Version,
+			Type:             event.Chat.Type,
+			SubscribersCount: event.Chat.SubscribersCount,
+			Public:           event.Chat.Public,
+		}
+	}
+
+	switch event.Type {
+	case models.OutboxChatCreated:
+		msg.Payload = &events.Event_ChatCreated{ChatCreated: &events.ChatCreated{UserId: data.UserId}}
+	case models.OutboxChatUpdated:
+		msg.Payload = &events.Event_ChatUpdated{ChatUpdated: &events.ChatUpdated{UserId: data.UserId}}
+	case models.OutboxParticipantAdded:
+		msg.Payload = &events.Event_ParticipantAdded{ParticipantAdded: &events.ParticipantAdded{
+			UserId:        data.UserId,
+			ParticipantId: data.ParticipantId,
+		}}
+	case models.OutboxChatDeleted:
+		msg.Payload = &events.Event_ChatDeleted{ChatDeleted: &events.ChatDeleted{UserId: data.UserId}}
+	case models.OutboxChatRestored:
+		msg.Payload = &events.Event_ChatRestored{ChatRestored: &events.ChatRestored{UserId: data.UserId}}
+	case models.OutboxJoinRequested:
+		msg.Payload = &events.Event_JoinRequested{JoinRequested: &events.JoinRequested{
+			UserId:    data.UserId,
+			RequestId: data.RequestId,
+		}}
+	case models.OutboxJoinApproved:
+		msg.Payload = &events.Event_JoinApproved{JoinApproved: &events.JoinDecided{
+			UserId:      data.UserId,
+			RequestId:   data.RequestId,
+			RequesterId: data.RequesterId,
+		}}
+	case models.OutboxJoinRejected:
+		msg.Payload = &events.Event_JoinRejected{JoinRejected: &events.JoinDecided{
+			UserId:      data.UserId,
+			RequestId:   data.RequestId,
+			RequesterId: data.RequesterId,
+		}}
+	case models.OutboxUserBanned:
+		msg.Payload = &events.Event_UserBanned{UserBanned: &events.UserBanned{
+			UserId:   data.UserId,
+			BannedId: data.BannedId,
+			Reason:   data.Reason,
+		}}
+	case models.OutboxUserUnbanned:
+		msg.Payload = &events.Event_UserUnbanned{UserUnbanned: &events.UserUnbanned{
+			UserId:     data.UserId,
+			UnbannedId: data.UnbannedId,
+		}}
+	case models.OutboxUserMuted:
+		msg.Payload = &events.Event_UserMuted{UserMuted: &events.UserMuted{
+			UserId:  data.UserId,
+			MutedId: data.MutedId,
+			Until:   timestamppb.New(data.Until),
+		}}
+	case models.OutboxUserUnmuted:
+		msg.Payload = &events.Event_UserUnmuted{UserUnmuted: &events.UserUnmuted{
+			UserId:    data.UserId,
+			UnmutedId: data.UnmutedId,
+		}}
+	}
+
+	return proto.Marshal(msg)
+}
@@ -0,0 +1,238 @@
+package nats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/pkg/api/events"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	replyAck = iota
+	replyError
+	replyNothing
+)
+
+type message struct {
+	subject string
+	headers string
+	payload []byte
+}
+
+// fakeServer speaks enough of NATS protocol to answer JetStream publishes
+// with an acknowledgement, an api error or nothing at all.
+type fakeServer struct {
+	lis      net.Listener
+	messages chan message
+	reply    int
+}
+
+func newFakeServer(t *testing.T, reply int) *fakeServer {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	s := &fakeServer{
+		lis:      lis,
+		messages: make(chan message, 10),
+		reply:    reply,
+	}
+	go s.serve()
+	t.Cleanup(func() { lis.Close() })
+
+	return s
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.lis.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	fmt.Fprint(conn, "INFO {\"server_id\":\"fake\",\"headers\":true,\"max_payload\":1048576,\"proto\":1}\r\n")
+
+	// reply inbox subscription, acks are delivered to it
+	var sid string
+	var seq int
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+		case line == "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		case strings.HasPrefix(line, "SUB "):
+			fields := strings.Fields(line)
+			sid = fields[len(fields)-1]
+		case strings.HasPrefix(line, "HPUB "):
+			fields := strings.Fields(line)
+			if len(fields) != 5 {
+				return
+			}
+			headerLen, _ := strconv.Atoi(fields[3])
+			totalLen, _ := strconv.Atoi(fields[4])
+
+			body := make([]byte, totalLen+2)
+			if _, err := io.ReadFull(reader, body); err != nil {
+				return
+			}
+			s.messages <- message{
+				subject: fields[1],
+				headers: string(body[:headerLen]),
+				payload: body[headerLen:totalLen],
+			}
+
+			var ack string
+			switch s.reply {
+			case replyAck:
+				seq++
+				ack = fmt.Sprintf(`{"stream":"CHAT","seq":%d}`, seq)
+			case replyError:
+				ack = `{"error":{"code":503,"err_code":10039,"description":"jetstream not enabled"}}`
+			default:
+				continue
+			}
+			fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", fields[2], sid, len(ack), ack)
+		}
+	}
+}
+
+func newNATS(t *testing.T, server *fakeServer) *NATS {
+	t.Helper()
+
+	nc, err := nats.Connect("nats://"+server.lis.Addr().String(), nats.MaxReconnects(0))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(nc.Close)
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("failed to init jetstream: %v", err)
+	}
+
+	return New(js, "speak", 200*time.Millisecond)
+}
+
+func TestNATS_Publish(t *testing.T) {
+	server := newFakeServer(t, replyAck)
+	n := newNATS(t, server)
+
+	event := models.Event{
+		ID:      "42",
+		Type:    models.OutboxChatCreated,
+		Version: models.EventSchemaVersion,
+		ChatId:  "chat",
+		Chat:    &models.EventChat{ID: "chat", Name: "name"},
+		Data:    []byte(`{"user_id": "owner"}`),
+	}
+
+	for range 2 {
+		err := n.Publish(context.Background(), event)
+		if err != nil {
+			t.Fatalf("NATS.Publish() error = %v", err)
+		}
+
+		msg := <-server.messages
+		if msg.subject != "speak.chat.created" {
+			t.Errorf("subject = %q, want %q", msg.subject, "speak.chat.created")
+		}
+		if !strings.Contains(msg.headers, "Nats-Msg-Id: 42") {
+			t.Errorf("headers = %q, want message id", msg.headers)
+		}
+
+		var got events.Event
+		if err := proto.Unmarshal(msg.payload, &got); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+		if got.Id != event.ID || got.Version != models.EventSchemaVersion || got.GetChat().GetName() != "name" {
+			t.Errorf("published event = %v, want %+v", &got, event)
+		}
+		if got.GetChatCreated().GetUserId() != "owner" {
+			t.Errorf("published payload = %v, want chat created by owner", got.GetPayload())
+		}
+	}
+}
+
+func TestNATS_PublishNotAcked(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply int
+	}{
+		{
+			name:  "stream error",
+			reply: replyError,
+		},
+		{
+			name:  "no ack",
+			reply: replyNothing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, tt.reply)
+			n := newNATS(t, server)
+
+			err := n.Publish(context.Background(), models.Event{ID: "1", Type: models.OutboxChatDeleted})
+			if err == nil {
+				t.Fatal("NATS.Publish() error = nil, want error without ack")
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	until := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	payload, err := marshal(models.Event{
+		ID:     "7",
+		Type:   models.OutboxUserMuted,
+		ChatId: "chat",
+		Data:   []byte(`{"user_id": "owner", "muted_id": "user", "until": "2026-10-18T15:00:00+03:00"}`),
+	})
+	if err != nil {
+		t.Fatalf("marshal() error = %v", err)
+	}
+
+	var got events.Event
+	if err := proto.Unmarshal(payload, &got); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+
+	muted := got.GetUserMuted()
+	if muted.GetUserId() != "owner" || muted.GetMutedId() != "user" {
+		t.Errorf("muted = %v, want user muted by owner", muted)
+	}
+	if !muted.GetUntil().AsTime().Equal(until) {
+		t.Errorf("muted until = %v, want %v", muted.GetUntil().AsTime(), until)
+	}
+	if got.Chat != nil {
+		t.Errorf("chat = %v, want nil for missing chat", got.Chat)
+	}
+}
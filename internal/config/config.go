@@ -16,6 +16,12 @@ type Config struct {
 	DB              DBConfig
 	S3              MinioConfig
 	Redis           RedisConfig
+	GC              GCConfig
+	Outbox          OutboxConfig
+	Purge           PurgeConfig
+	Audit           AuditConfig
+	Events          EventsConfig
+	Webhook         WebhookConfig
 }
 
 type DBConfig struct {
@@ -30,20 +36,82 @@ type DBConfig struct {
 }
 
 type MinioConfig struct {
-	Endpoint   string        `env:"MINIO_ENDPOINT" env-default:"localhost:9000"`
-	User       string        `env:"MINIO_ROOT_USER" env-default:"minio"`
-	Password   string        `env:"MINIO_ROOT_PASSWORD" env-required:"true"`
-	BucketName string        `env:"MINIO_BUCKET_NAME" env-default:"users"`
-	IsUseSsl   bool          `env:"MINIO_USE_SSL" env-default:"false"`
-	Expires    time.Duration `env:"MINIO_EXPIRES" env-default:"140h"`
+	Endpoint      string        `env:"MINIO_ENDPOINT" env-default:"localhost:9000"`
+	User          string        `env:"MINIO_ROOT_USER" env-default:"minio"`
+	Password      string        `env:"MINIO_ROOT_PASSWORD" env-required:"true"`
+	BucketName    string        `env:"MINIO_BUCKET_NAME" env-default:"users"`
+	IsUseSsl      bool          `env:"MINIO_USE_SSL" env-default:"false"`
+	Expires       time.Duration `env:"MINIO_EXPIRES" env-default:"140h"`
+	URLMode       string        `env:"MINIO_URL_MODE" env-default:"presigned"`
+	PublicBaseUrl string        `env:"MINIO_PUBLIC_BASE_URL"`
+	Backend       string        `env:"S3_BACKEND" env-default:"minio"`
+	LocalDir      string        `env:"S3_LOCAL_DIR" env-default:"./data/avatars"`
+	LocalAddr     string        `env:"S3_LOCAL_ADDR" env-default:":50031"`
+	LocalBaseUrl  string        `env:"S3_LOCAL_BASE_URL" env-default:"http://localhost:50031"`
+	LocalSecret   string        `env:"S3_LOCAL_SECRET"`
 }
 
 type RedisConfig struct {
-	Addr       string        `env:"REDIS_ADDR" env-default:"localhost:6379"`
-	User       string        `env:"REDIS_USER" env-default:"root"`
-	Password   string        `env:"REDIS_USER_PASSWORD" env-default:"root"`
-	DB         int           `env:"REDIS_DB" env-default:"0"`
-	Expiration time.Duration `env:"REDIS_EXPIRATION" env-default:"24h"`
+	Addr               string        `env:"REDIS_ADDR" env-default:"localhost:6379"`
+	User               string        `env:"REDIS_USER" env-default:"root"`
+	Password           string        `env:"REDIS_USER_PASSWORD" env-default:"root"`
+	DB                 int           `env:"REDIS_DB" env-default:"0"`
+	Expiration         time.Duration `env:"REDIS_EXPIRATION" env-default:"24h"`
+	NegativeExpiration time.Duration `env:"REDIS_NEGATIVE_EXPIRATION" env-default:"30s"`
+	EarlyRefreshDelta  time.Duration `env:"REDIS_EARLY_REFRESH_DELTA" env-default:"1s"`
+	LocalCacheSize     int           `env:"LOCAL_CACHE_SIZE" env-default:"10000"`
+	LocalCacheTTL      time.Duration `env:"LOCAL_CACHE_TTL" env-default:"5s"`
+}
+
+type GCConfig struct {
+	Enabled     bool          `env:"GC_ENABLED" env-default:"true"`
+	Interval    time.Duration `env:"GC_INTERVAL" env-default:"24h"`
+	GracePeriod time.Duration `env:"GC_GRACE_PERIOD" env-default:"24h"`
+	DryRun      bool          `env:"GC_DRY_RUN" env-default:"false"`
+}
+
+type OutboxConfig struct {
+	Interval   time.Duration `env:"OUTBOX_INTERVAL" env-default:"1s"`
+	BatchSize  int           `env:"OUTBOX_BATCH_SIZE" env-default:"100"`
+	Lease      time.Duration `env:"OUTBOX_LEASE" env-default:"30s"`
+	MaxBackoff time.Duration `env:"OUTBOX_MAX_BACKOFF" env-default:"5m"`
+}
+
+type PurgeConfig struct {
+	Enabled       bool          `env:"PURGE_ENABLED" env-default:"true"`
+	Interval      time.Duration `env:"PURGE_INTERVAL" env-default:"1h"`
+	RestoreWindow time.Duration `env:"PURGE_RESTORE_WINDOW" env-default:"720h"`
+	BatchSize     int           `env:"PURGE_BATCH_SIZE" env-default:"100"`
+}
+
+type AuditConfig struct {
+	PartitionInterval time.Duration `env:"AUDIT_PARTITION_INTERVAL" env-default:"24h"`
+	PartitionsAhead   int           `env:"AUDIT_PARTITIONS_AHEAD" env-default:"2"`
+}
+
+type EventsConfig struct {
+	// Backend is "nats", "memory" or empty to disable events
+	Backend       string        `env:"EVENTS_BACKEND"`
+	NatsAddr      string        `env:"EVENTS_NATS_ADDR" env-default:"localhost:4222"`
+	NatsUser      string        `env:"EVENTS_NATS_USER"`
+	NatsPassword  string        `env:"EVENTS_NATS_PASSWORD"`
+	NatsToken     string        `env:"EVENTS_NATS_TOKEN"`
+	NatsCredsFile string        `env:"EVENTS_NATS_CREDS_FILE"`
+	NatsTLSCA     string        `env:"EVENTS_NATS_TLS_CA"`
+	NatsTLSCert   string        `env:"EVENTS_NATS_TLS_CERT"`
+	NatsTLSKey    string        `env:"EVENTS_NATS_TLS_KEY"`
+	SubjectPrefix string        `env:"EVENTS_SUBJECT_PREFIX" env-default:"speak"`
+	Timeout       time.Duration `env:"EVENTS_TIMEOUT" env-default:"5s"`
+}
+
+type WebhookConfig struct {
+	Enabled     bool          `env:"WEBHOOK_ENABLED" env-default:"true"`
+	Interval    time.Duration `env:"WEBHOOK_INTERVAL" env-default:"1s"`
+	BatchSize   int           `env:"WEBHOOK_BATCH_SIZE" env-default:"100"`
+	Lease       time.Duration `env:"WEBHOOK_LEASE" env-default:"1m"`
+	MaxAttempts int           `env:"WEBHOOK_MAX_ATTEMPTS" env-default:"10"`
+	MaxBackoff  time.Duration `env:"WEBHOOK_MAX_BACKOFF" env-default:"1h"`
+	Timeout     time.Duration `env:"WEBHOOK_TIMEOUT" env-default:"10s"`
 }
 
 func MustLoad() *Config {
@@ -3,12 +3,17 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/internal/service"
+	"github.com/AlexMickh/speak-chat/internal/storage"
 	"github.com/AlexMickh/speak-chat/pkg/logger"
 	"github.com/AlexMickh/speak-protos/pkg/api/chat"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -32,10 +37,17 @@ type Service interface {
 		name string,
 		description string,
 		avatar []byte,
+		expectedVersion int64,
 	) (models.Chat, error)
-	DeleteChat(ctx context.Context, userId, chatId string) error
+	DeleteChat(ctx context.Context, userId, chatId string, expectedVersion int64) error
 }
 
+// Chat version is exchanged in metadata the same way as http etags.
+const (
+	etagHeader    = "etag"
+	ifMatchHeader = "if-match"
+)
+
 type AuthClient interface {
 	GetUserId(ctx context.Context, token string) (string, error)
 }
@@ -102,10 +114,15 @@ func (s *Server) GetChat(ctx context.Context, req *chat.GetChatRequest) (*chat.G
 
 	chatInfo, err := s.service.GetChat(ctx, req.GetId())
 	if err != nil {
+		if errors.Is(err, storage.ErrChatNotFound) {
+			return nil, status.Error(codes.NotFound, "chat not found")
+		}
 		logger.GetFromCtx(ctx).Error(ctx, "failed to get chat", zap.Error(err))
 		return nil, status.Error(codes.Internal, "failed to get chat")
 	}
 
+	setETag(ctx, chatInfo.Version)
+
 	return &chat.GetChatResponse{
 		Chat: &chat.ChatType{
 			Id:             chatInfo.ID,
@@ -150,6 +167,15 @@ func (s *Server) AddParticipant(ctx context.Context, req *chat.AddParticipantReq
 
 	err = s.service.AddParticipant(ctx, userId, req.GetChatId(), req.GetParticipantId())
 	if err != nil {
+		if errors.Is(err, storage.ErrChatNotFound) {
+			return nil, status.Error(codes.NotFound, "chat not found")
+		}
+		if errors.Is(err, service.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "only owner can add participants")
+		}
+		if errors.Is(err, service.ErrUserBanned) {
+			return nil, status.Error(codes.FailedPrecondition, "participant is banned in the chat")
+		}
 		logger.GetFromCtx(ctx).Error(ctx, "failed to add participant to the chat", zap.Error(err))
 		return nil, status.Error(codes.Internal, "failed to add participant to the chat")
 	}
@@ -187,6 +213,11 @@ func (s *Server) UpdateChatInfo(ctx context.Context, req *chat.UpdateChatInfoReq
 		return nil, status.Error(codes.Internal, "failed to get user id")
 	}
 
+	expectedVersion, err := getExpectedVersion(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	chatInfo, err := s.service.UpdateChatInfo(
 		ctx,
 		userId,
@@ -194,12 +225,24 @@ func (s *Server) UpdateChatInfo(ctx context.Context, req *chat.UpdateChatInfoReq
 		req.GetName(),
 		req.GetDescription(),
 		req.GetChatImage(),
+		expectedVersion,
 	)
 	if err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "chat was modified concurrently")
+		}
+		if errors.Is(err, storage.ErrChatNotFound) {
+			return nil, status.Error(codes.NotFound, "chat not found")
+		}
+		if errors.Is(err, service.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "only owner can update chat")
+		}
 		logger.GetFromCtx(ctx).Error(ctx, "failed to update chat info", zap.Error(err))
 		return nil, status.Error(codes.Internal, "failed to update chat info")
 	}
 
+	setETag(ctx, chatInfo.Version)
+
 	return &chat.UpdateChatInfoResponse{
 		Chat: &chat.ChatType{
 			Id:             chatInfo.ID,
@@ -237,8 +280,22 @@ func (s *Server) DeleteChat(ctx context.Context, req *chat.DeleteChatRequest) (*
 		return nil, status.Error(codes.Internal, "failed to get user id")
 	}
 
-	err = s.service.DeleteChat(ctx, userId, req.GetId())
+	expectedVersion, err := getExpectedVersion(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = s.service.DeleteChat(ctx, userId, req.GetId(), expectedVersion)
 	if err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			return nil, status.Error(codes.Aborted, "chat was modified concurrently")
+		}
+		if errors.Is(err, storage.ErrChatNotFound) {
+			return nil, status.Error(codes.NotFound, "chat not found")
+		}
+		if errors.Is(err, service.ErrPermissionDenied) {
+			return nil, status.Error(codes.PermissionDenied, "only owner can delete chat")
+		}
 		logger.GetFromCtx(ctx).Error(ctx, "failed to delete chat", zap.Error(err))
 		return nil, status.Error(codes.Internal, "failed to delete chat")
 	}
@@ -272,3 +329,31 @@ func getAuthToken(ctx context.Context) (string, error) {
 
 	return token, nil
 }
+
+// getExpectedVersion returns chat version from if-match header.
+// Zero is returned when header is absent, so no check is made.
+func getExpectedVersion(ctx context.Context) (int64, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, nil
+	}
+
+	values := md.Get(ifMatchHeader)
+	if len(values) == 0 || values[0] == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(values[0], `"`), 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid %s header", ifMatchHeader)
+	}
+
+	return version, nil
+}
+
+func setETag(ctx context.Context, version int64) {
+	err := grpc.SetHeader(ctx, metadata.Pairs(etagHeader, strconv.Quote(strconv.FormatInt(version, 10))))
+	if err != nil {
+		logger.GetFromCtx(ctx).Error(ctx, "failed to set etag", zap.Error(err))
+	}
+}
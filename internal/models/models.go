@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -10,10 +11,27 @@ type Chat struct {
 	Description     string    `redis:"description"`
 	ChatImageUrl    string    `redis:"chat_image_url"`
 	ImageExpireTime time.Time `redis:"image_expire_time"`
+	AvatarHash      string    `redis:"avatar_hash"`
 	ChatOwnerId     string    `redis:"chat_owner_id"`
-	ParticipantsId  []string  `redis:"-"`
+	Version         int64     `redis:"version"`
+	Type            string    `redis:"type"`
+	// SubscribersCount is set for channels, their subscribers are not
+	// part of ParticipantsId
+	SubscribersCount int64 `redis:"subscribers_count"`
+	// Public chats are listed in directory and can be joined without invite
+	Public         bool     `redis:"public"`
+	ParticipantsId []string `redis:"-"`
 }
 
+// Chat types. Direct chat is a conversation of two users,
+// it has no name, avatar and owner of its own. Only admins post
+// to channel, subscribers read and react.
+const (
+	ChatTypeGroup   = "group"
+	ChatTypeDirect  = "direct"
+	ChatTypeChannel = "channel"
+)
+
 type ChatPreview struct {
 	ID              string    `redis:"id"`
 	Name            string    `redis:"name"`
@@ -21,7 +39,197 @@ type ChatPreview struct {
 	ImageExpireTime time.Time `redis:"image_expire_time"`
 }
 
+// PublicChat is a chat directory search result.
+type PublicChat struct {
+	ID              string
+	Name            string
+	Description     string
+	ChatImageUrl    string
+	ImageExpireTime time.Time
+	AvatarHash      string
+	Type            string
+	MembersCount    int64
+}
+
 type Avatar struct {
 	ID   string
 	Data []byte
 }
+
+type AvatarObject struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+type AvatarVersion struct {
+	ID         int64
+	ChatId     string
+	AvatarHash string
+	ImageUrl   string
+	CreatedBy  string
+	CreatedAt  time.Time
+}
+
+// Kinds of outbox entries.
+const (
+	OutboxChatCreated      = "chat.created"
+	OutboxChatUpdated      = "chat.updated"
+	OutboxParticipantAdded = "chat.participant_added"
+	OutboxChatDeleted      = "chat.deleted"
+	OutboxChatRestored     = "chat.restored"
+	OutboxJoinRequested    = "chat.join_requested"
+	OutboxJoinApproved     = "chat.join_approved"
+	OutboxJoinRejected     = "chat.join_rejected"
+	OutboxUserBanned       = "chat.user_banned"
+	OutboxUserUnbanned     = "chat.user_unbanned"
+	OutboxUserMuted        = "chat.user_muted"
+	OutboxUserUnmuted      = "chat.user_unmuted"
+)
+
+type OutboxEntry struct {
+	ID        int64
+	ChatId    string
+	Kind      string
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Audited actions.
+const (
+	AuditChatCreated      = "chat.created"
+	AuditChatUpdated      = "chat.updated"
+	AuditParticipantAdded = "chat.participant_added"
+	AuditChatDeleted      = "chat.deleted"
+	AuditChatRestored     = "chat.restored"
+	AuditAvatarRestored   = "chat.avatar_restored"
+	AuditUserBanned       = "chat.user_banned"
+	AuditUserUnbanned     = "chat.user_unbanned"
+	AuditUserMuted        = "chat.user_muted"
+	AuditUserUnmuted      = "chat.user_unmuted"
+	AuditWebhookAdded     = "chat.webhook_added"
+	AuditWebhookDeleted   = "chat.webhook_deleted"
+	AuditInviteCreated    = "chat.invite_created"
+	AuditInviteRevoked    = "chat.invite_revoked"
+	AuditJoinRequested    = "chat.join_requested"
+	AuditJoinRejected     = "chat.join_rejected"
+)
+
+type AuditEntry struct {
+	ID        int64
+	ChatId    string
+	ActorId   string
+	Action    string
+	Diff      map[string]AuditChange
+	RequestId string
+	CreatedAt time.Time
+}
+
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// EventSchemaVersion is bumped on every incompatible change of Event.
+const EventSchemaVersion = 1
+
+// EventWebhookTest is sent only to the webhook being tested.
+const EventWebhookTest = "webhook.test"
+
+// Event is published to other services on every committed chat change.
+// Type is one of outbox kinds. ID is stable across redeliveries,
+// so consumers can drop duplicates.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	ChatId     string          `json:"chat_id"`
+	Chat       *EventChat      `json:"chat,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// EventChat is chat state after the change. Image url is left out,
+// it expires and is meaningless to other services.
+type EventChat struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Description      string   `json:"description"`
+	AvatarHash       string   `json:"avatar_hash"`
+	OwnerId          string   `json:"owner_id"`
+	ParticipantsId   []string `json:"participants_id"`
+	Version          int64    `json:"version"`
+	Type             string   `json:"type"`
+	SubscribersCount int64    `json:"subscribers_count"`
+	Public           bool     `json:"public"`
+}
+
+type Webhook struct {
+	ID        string
+	ChatId    string
+	Url       string
+	Secret    string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	ID        int64
+	WebhookId string
+	Url       string
+	Secret    string
+	EventId   string
+	EventType string
+	Payload   []byte
+	Attempts  int
+}
+
+// Invite lets users join chat without owner adding them.
+// Zero ExpiresAt never expires, zero MaxUses is unlimited.
+type Invite struct {
+	Code      string
+	ChatId    string
+	CreatedBy string
+	ExpiresAt time.Time
+	MaxUses   int
+	Uses      int
+	CreatedAt time.Time
+}
+
+// Statuses of join requests.
+const (
+	JoinRequestPending  = "pending"
+	JoinRequestApproved = "approved"
+	JoinRequestRejected = "rejected"
+)
+
+type JoinRequest struct {
+	ID        string
+	ChatId    string
+	UserId    string
+	Message   string
+	Status    string
+	DecidedBy string
+	DecidedAt time.Time
+	CreatedAt time.Time
+}
+
+// Ban removes user from chat and stops them from joining it again.
+type Ban struct {
+	ChatId    string
+	UserId    string
+	Reason    string
+	BannedBy  string
+	CreatedAt time.Time
+}
+
+// Mute stops participant from posting to chat until given time.
+type Mute struct {
+	ChatId    string
+	UserId    string
+	Reason    string
+	MutedBy   string
+	Until     time.Time
+	CreatedAt time.Time
+}
@@ -2,14 +2,51 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/internal/storage"
+	"github.com/AlexMickh/speak-chat/pkg/logger"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrPermissionDenied  = errors.New("permission denied")
+	ErrInvalidPageToken  = errors.New("invalid page token")
+	ErrInvalidWebhookUrl = errors.New("webhook url must be absolute http or https url")
+	ErrInvalidInvite     = errors.New("invite ttl and max uses must not be negative")
+	ErrAlreadyJoined     = errors.New("user is already chat participant")
+	ErrUserBanned        = errors.New("user is banned in chat")
+	ErrUserMuted         = errors.New("user is muted in chat")
+	ErrInvalidModeration = errors.New("owner can not be banned or muted and mute must end in the future")
+	ErrInvalidDirectChat = errors.New("direct chat needs another user")
+	ErrDirectChat        = errors.New("direct chat can not be joined")
+	ErrEmptySearchQuery  = errors.New("search query is empty")
+)
+
+const (
+	defaultAuditPageSize       = 50
+	maxAuditPageSize           = 100
+	defaultSubscribersPageSize = 100
+	maxSubscribersPageSize     = 1000
+	defaultSearchPageSize      = 20
+	maxSearchPageSize          = 50
 )
 
 type Storage interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
 	SaveChat(
 		ctx context.Context,
 		id string,
@@ -17,7 +54,9 @@ type Storage interface {
 		description string,
 		chatImageUrl string,
 		imageExireTime time.Time,
+		avatarHash string,
 		chatOwnerId string,
+		chatType string,
 	) error
 	GetChat(ctx context.Context, id string) (models.Chat, error)
 	AddParticipant(
@@ -25,7 +64,7 @@ type Storage interface {
 		userId string,
 		chatId string,
 		participantId string,
-	) error
+	) (models.Chat, error)
 	UpdateChatInfo(
 		ctx context.Context,
 		userId string,
@@ -34,6 +73,8 @@ type Storage interface {
 		description string,
 		chatImageUrl string,
 		imageExireTime time.Time,
+		avatarHash string,
+		expectedVersion int64,
 	) (models.Chat, error)
 	UpdateImageUrl(
 		ctx context.Context,
@@ -41,35 +82,90 @@ type Storage interface {
 		chatImageUrl string,
 		imageExireTime time.Time,
 	) (models.Chat, error)
-	DeleteChat(ctx context.Context, userId, chatId string, ch chan error)
+	DeleteChat(ctx context.Context, userId, chatId string, expectedVersion int64, ch chan error)
+	RestoreChat(ctx context.Context, userId, chatId string, window time.Duration) (models.Chat, error)
+	LockChat(ctx context.Context, id string) (models.Chat, error)
+	SaveAuditEntry(ctx context.Context, entry models.AuditEntry) error
+	GetAuditEntries(ctx context.Context, chatId string, beforeId int64, limit int) ([]models.AuditEntry, error)
+	AcquireAvatar(ctx context.Context, hash string, size int64) (bool, error)
+	GetAvatarVersions(ctx context.Context, chatId string) ([]models.AvatarVersion, error)
+	GetAvatarVersion(ctx context.Context, chatId string, versionId int64) (models.AvatarVersion, error)
+	SaveWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	GetWebhooks(ctx context.Context, chatId string) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, chatId, webhookId string) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, chatId, webhookId string) error
+	JoinChat(ctx context.Context, chatId, participantId, actorId string) (models.Chat, error)
+	SaveInvite(ctx context.Context, invite models.Invite) (models.Invite, error)
+	GetInvites(ctx context.Context, chatId string) ([]models.Invite, error)
+	RevokeInvite(ctx context.Context, chatId, code string) error
+	ConsumeInvite(ctx context.Context, code string) (string, error)
+	SaveJoinRequest(ctx context.Context, request models.JoinRequest) (models.JoinRequest, error)
+	GetPendingJoinRequests(ctx context.Context, chatId string) ([]models.JoinRequest, error)
+	DecideJoinRequest(
+		ctx context.Context,
+		chatId string,
+		requestId string,
+		status string,
+		deciderId string,
+	) (models.JoinRequest, error)
+	BanUser(ctx context.Context, ban models.Ban) (models.Chat, error)
+	UnbanUser(ctx context.Context, chatId, userId, actorId string) error
+	IsBanned(ctx context.Context, chatId, userId string) (bool, error)
+	GetBans(ctx context.Context, chatId string) ([]models.Ban, error)
+	MuteUser(ctx context.Context, mute models.Mute) (models.Mute, error)
+	UnmuteUser(ctx context.Context, chatId, userId, actorId string) error
+	GetMute(ctx context.Context, chatId, userId string) (models.Mute, error)
+	GetMutes(ctx context.Context, chatId string) ([]models.Mute, error)
+	GetOrCreateDirectChat(ctx context.Context, id, userId, peerId string) (models.Chat, bool, error)
+	Subscribe(ctx context.Context, chatId, userId, actorId string) (models.Chat, error)
+	IsSubscribed(ctx context.Context, chatId, userId string) (bool, error)
+	GetSubscribers(ctx context.Context, chatId, afterUserId string, limit int) ([]string, error)
+	SetChatVisibility(ctx context.Context, userId, chatId string, public bool) (models.Chat, error)
+	SearchPublicChats(ctx context.Context, query string, offset, limit int) ([]models.PublicChat, error)
 }
 
 type Cash interface {
 	SaveChat(ctx context.Context, chat models.Chat) error
 	GetChat(ctx context.Context, id string) (models.Chat, error)
+	SaveMissingChat(ctx context.Context, id string) error
 	UpdateChat(ctx context.Context, chat models.Chat) error
-	AddParticipant(ctx context.Context, chatId, participantId string) error
 	DeleteChat(ctx context.Context, chatId string) error
 }
 
 type S3 interface {
 	SaveAvatar(ctx context.Context, avatar *models.Avatar) (string, time.Time, error)
 	GetAvatarUrl(ctx context.Context, avatarId string) (string, time.Time, error)
-	UpdateAvatar(ctx context.Context, avatar models.Avatar) (string, time.Time, error)
 	DeleteAvatar(ctx context.Context, avatarId string) (string, time.Time, error)
 }
 
+type WebhookSender interface {
+	Send(ctx context.Context, webhook models.Webhook, event models.Event) error
+}
+
 type Service struct {
-	storage Storage
-	cash    Cash
-	s3      S3
+	storage  Storage
+	cash     Cash
+	s3       S3
+	webhooks WebhookSender
+	// restoreWindow is how long deleted chat can be restored
+	restoreWindow time.Duration
+	// loads coalesces concurrent cache misses of the same chat
+	loads singleflight.Group
 }
 
-func New(storage Storage, cash Cash, s3 S3) *Service {
+func New(
+	storage Storage,
+	cash Cash,
+	s3 S3,
+	webhooks WebhookSender,
+	restoreWindow time.Duration,
+) *Service {
 	return &Service{
-		storage: storage,
-		cash:    cash,
-		s3:      s3,
+		storage:       storage,
+		cash:          cash,
+		s3:            s3,
+		webhooks:      webhooks,
+		restoreWindow: restoreWindow,
 	}
 }
 
@@ -82,51 +178,124 @@ func (s *Service) CreateChat(
 ) (string, error) {
 	const op = "service.CreateChat"
 
-	id := uuid.NewString()
-	var chatImageUrl string
-	var expires time.Time
-	var err error
-	if avatar != nil {
-		avatarStruct := &models.Avatar{
-			ID:   id,
-			Data: avatar,
-		}
-
-		chatImageUrl, expires, err = s.s3.SaveAvatar(ctx, avatarStruct)
-	} else {
-		chatImageUrl, expires, err = s.s3.SaveAvatar(ctx, nil)
-	}
+	id, err := s.createChat(ctx, models.ChatTypeGroup, name, description, avatar, chatOwnerId)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = s.storage.SaveChat(
-		ctx,
-		id,
-		name,
-		description,
-		chatImageUrl,
-		expires,
-		chatOwnerId,
-	)
+	return id, nil
+}
+
+// CreateChannel creates broadcast channel, only its owner can post to it.
+func (s *Service) CreateChannel(
+	ctx context.Context,
+	name string,
+	description string,
+	avatar []byte,
+	chatOwnerId string,
+) (string, error) {
+	const op = "service.CreateChannel"
+
+	id, err := s.createChat(ctx, models.ChatTypeChannel, name, description, avatar, chatOwnerId)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
+	return id, nil
+}
+
+func (s *Service) createChat(
+	ctx context.Context,
+	chatType string,
+	name string,
+	description string,
+	avatar []byte,
+	chatOwnerId string,
+) (string, error) {
+	const op = "service.createChat"
+
 	chat := models.Chat{
-		ID:             id,
+		ID:             uuid.NewString(),
 		Name:           name,
 		Description:    description,
-		ChatImageUrl:   chatImageUrl,
 		ChatOwnerId:    chatOwnerId,
+		Version:        1,
+		Type:           chatType,
 		ParticipantsId: []string{chatOwnerId},
 	}
-	err = s.cash.SaveChat(ctx, chat)
+	err := s.storage.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if avatar != nil {
+			chat.AvatarHash, chat.ChatImageUrl, chat.ImageExpireTime, err = s.acquireAvatar(ctx, avatar)
+		} else {
+			chat.ChatImageUrl, chat.ImageExpireTime, err = s.s3.SaveAvatar(ctx, nil)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = s.storage.SaveChat(
+			ctx,
+			chat.ID,
+			chat.Name,
+			chat.Description,
+			chat.ChatImageUrl,
+			chat.ImageExpireTime,
+			chat.AvatarHash,
+			chat.ChatOwnerId,
+			chat.Type,
+		)
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, chat.ID, chatOwnerId, models.AuditChatCreated, diffChats(models.Chat{}, chat))
+	})
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	return id, nil
+	// chat is already committed, cache is repaired by outbox relay
+	err = s.cash.SaveChat(ctx, chat)
+	if err != nil {
+		logger.GetFromCtx(ctx).Error(ctx, "failed to cache chat", zap.Error(err))
+	}
+
+	return chat.ID, nil
+}
+
+// GetOrCreateDirectChat returns the only direct chat of two users,
+// the order of users does not matter.
+func (s *Service) GetOrCreateDirectChat(ctx context.Context, userId, peerId string) (models.Chat, error) {
+	const op = "service.GetOrCreateDirectChat"
+
+	if peerId == "" || peerId == userId {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, ErrInvalidDirectChat)
+	}
+
+	var chat models.Chat
+	var created bool
+	err := s.storage.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		chat, created, err = s.storage.GetOrCreateDirectChat(ctx, uuid.NewString(), userId, peerId)
+		if err != nil || !created {
+			return err
+		}
+
+		return s.audit(ctx, chat.ID, userId, models.AuditChatCreated, diffChats(models.Chat{}, chat))
+	})
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if created {
+		err = s.cash.SaveChat(ctx, chat)
+		if err != nil {
+			logger.GetFromCtx(ctx).Error(ctx, "failed to cache chat", zap.Error(err))
+		}
+	}
+
+	return chat, nil
 }
 
 func (s *Service) GetChat(ctx context.Context, id string) (models.Chat, error) {
@@ -135,7 +304,7 @@ func (s *Service) GetChat(ctx context.Context, id string) (models.Chat, error) {
 	chat, err := s.cash.GetChat(ctx, id)
 	if err == nil {
 		if s.isImageExpire(chat.ImageExpireTime) {
-			chat.ChatImageUrl, chat.ImageExpireTime, err = s.updateImageUrl(ctx, chat.ID)
+			chat.ChatImageUrl, chat.ImageExpireTime, err = s.updateImageUrl(ctx, chat.ID, chat.AvatarHash)
 			if err != nil {
 				return models.Chat{}, fmt.Errorf("%s: %w", op, err)
 			}
@@ -143,13 +312,41 @@ func (s *Service) GetChat(ctx context.Context, id string) (models.Chat, error) {
 
 		return chat, nil
 	}
+	if errors.Is(err, storage.ErrChatNotFound) {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if !errors.Is(err, storage.ErrCacheMiss) {
+		logger.GetFromCtx(ctx).Error(ctx, "failed to get chat from cache", zap.Error(err))
+	}
+
+	// load is shared by all waiting callers, so it must not depend
+	// on cancellation of the one that started it
+	loaded, err, _ := s.loads.Do(id, func() (any, error) {
+		return s.loadChat(context.WithoutCancel(ctx), id)
+	})
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
 
-	chat, err = s.storage.GetChat(ctx, id)
+	return loaded.(models.Chat), nil
+}
+
+// loadChat reads chat from database and puts it into cache.
+// Nonexistent chats are cached as missing.
+func (s *Service) loadChat(ctx context.Context, id string) (models.Chat, error) {
+	const op = "service.loadChat"
+
+	chat, err := s.storage.GetChat(ctx, id)
 	if err != nil {
+		if errors.Is(err, storage.ErrChatNotFound) {
+			if err := s.cash.SaveMissingChat(ctx, id); err != nil {
+				logger.GetFromCtx(ctx).Error(ctx, "failed to cache missing chat", zap.Error(err))
+			}
+		}
 		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
 	}
 	if s.isImageExpire(chat.ImageExpireTime) {
-		chat.ChatImageUrl, chat.ImageExpireTime, err = s.updateImageUrl(ctx, chat.ID)
+		chat.ChatImageUrl, chat.ImageExpireTime, err = s.updateImageUrl(ctx, chat.ID, chat.AvatarHash)
 		if err != nil {
 			return models.Chat{}, fmt.Errorf("%s: %w", op, err)
 		}
@@ -157,7 +354,7 @@ func (s *Service) GetChat(ctx context.Context, id string) (models.Chat, error) {
 
 	err = s.cash.SaveChat(ctx, chat)
 	if err != nil {
-		fmt.Println(err)
+		logger.GetFromCtx(ctx).Error(ctx, "failed to cache chat", zap.Error(err))
 	}
 
 	return chat, nil
@@ -166,19 +363,26 @@ func (s *Service) GetChat(ctx context.Context, id string) (models.Chat, error) {
 func (s *Service) AddParticipant(ctx context.Context, userId, chatId, participantId string) error {
 	const op = "service.AddParticipant"
 
-	err := s.storage.AddParticipant(ctx, userId, chatId, participantId)
+	var chat models.Chat
+	err := s.storage.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		chat, err = s.addParticipant(ctx, userId, chatId, participantId)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = s.cash.AddParticipant(ctx, chatId, participantId)
+	// whole chat is replaced, so cached version follows the database
+	err = s.cash.UpdateChat(ctx, chat)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		logger.GetFromCtx(ctx).Error(ctx, "failed to cache chat", zap.Error(err))
 	}
 
 	return nil
 }
 
+// UpdateChatInfo updates chat. Zero expected version skips concurrency check.
 func (s *Service) UpdateChatInfo(
 	ctx context.Context,
 	userId string,
@@ -186,85 +390,1233 @@ func (s *Service) UpdateChatInfo(
 	name string,
 	description string,
 	avatar []byte,
+	expectedVersion int64,
 ) (models.Chat, error) {
 	const op = "servive.UpdateChatInfo"
 
-	var url string
-	var expires time.Time
-	var err error
-	if avatar != nil {
-		avatarStruct := models.Avatar{
-			ID:   chatId,
-			Data: avatar,
+	var chat models.Chat
+	err := s.storage.WithTx(ctx, func(ctx context.Context) error {
+		before, err := s.storage.LockChat(ctx, chatId)
+		if err != nil {
+			return err
+		}
+		if before.ChatOwnerId != userId {
+			return ErrPermissionDenied
+		}
+
+		var url string
+		var expires time.Time
+		var avatarHash string
+		if avatar != nil {
+			avatarHash, url, expires, err = s.acquireAvatar(ctx, avatar)
+			if err != nil {
+				return err
+			}
 		}
 
-		url, expires, err = s.s3.UpdateAvatar(ctx, avatarStruct)
+		chat, err = s.storage.UpdateChatInfo(
+			ctx,
+			userId,
+			chatId,
+			name,
+			description,
+			url,
+			expires,
+			avatarHash,
+			expectedVersion,
+		)
 		if err != nil {
-			return models.Chat{}, nil
+			return err
 		}
-	}
 
-	chat, err := s.storage.UpdateChatInfo(
-		ctx,
-		userId,
-		chatId,
-		name,
-		description,
-		url,
-		expires,
-	)
+		return s.audit(ctx, chatId, userId, models.AuditChatUpdated, diffChats(before, chat))
+	})
 	if err != nil {
-		return models.Chat{}, nil
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	err = s.cash.UpdateChat(ctx, chat)
 	if err != nil {
-		return models.Chat{}, nil
+		logger.GetFromCtx(ctx).Error(ctx, "failed to cache chat", zap.Error(err))
 	}
 
 	return chat, nil
 }
 
-func (s *Service) DeleteChat(ctx context.Context, userId, chatId string) error {
+// DeleteChat deletes chat. Zero expected version skips concurrency check.
+func (s *Service) DeleteChat(ctx context.Context, userId, chatId string, expectedVersion int64) error {
 	const op = "service.DeleteChat"
 
-	ch := make(chan error)
-	go s.storage.DeleteChat(ctx, userId, chatId, ch)
+	err := s.storage.WithTx(ctx, func(ctx context.Context) error {
+		chat, err := s.storage.LockChat(ctx, chatId)
+		if err != nil {
+			return err
+		}
+		if chat.ChatOwnerId != userId {
+			return ErrPermissionDenied
+		}
+
+		ch := make(chan error)
+		go s.storage.DeleteChat(ctx, userId, chatId, expectedVersion, ch)
+
+		select {
+		case err = <-ch:
+			if err != nil {
+				return err
+			}
+		}
 
-	err := s.cash.DeleteChat(ctx, chatId)
+		return s.audit(ctx, chatId, userId, models.AuditChatDeleted, nil)
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	select {
-	case err = <-ch:
+	err = s.cash.DeleteChat(ctx, chatId)
+	if err != nil {
+		logger.GetFromCtx(ctx).Error(ctx, "failed to delete chat from cache", zap.Error(err))
+	}
+
+	return nil
+}
+
+// RestoreChat brings back chat deleted by its owner within restore window.
+func (s *Service) RestoreChat(ctx context.Context, userId, chatId string) (models.Chat, error) {
+	const op = "service.RestoreChat"
+
+	var chat models.Chat
+	err := s.storage.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		chat, err = s.storage.RestoreChat(ctx, userId, chatId, s.restoreWindow)
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, chatId, userId, models.AuditChatRestored, nil)
+	})
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.cash.UpdateChat(ctx, chat)
+	if err != nil {
+		logger.GetFromCtx(ctx).Error(ctx, "failed to cache chat", zap.Error(err))
+	}
+
+	return chat, nil
+}
+
+// ListAvatarVersions returns avatar history of the chat, newest first.
+// Only chat owner can see it.
+func (s *Service) ListAvatarVersions(ctx context.Context, userId, chatId string) ([]models.AvatarVersion, error) {
+	const op = "service.ListAvatarVersions"
+
+	chat, err := s.storage.GetChat(ctx, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if chat.ChatOwnerId != userId {
+		return nil, fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+
+	versions, err := s.storage.GetAvatarVersions(ctx, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range versions {
+		versions[i].ImageUrl, _, err = s.s3.GetAvatarUrl(ctx, versions[i].AvatarHash)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return versions, nil
+}
+
+// RestoreAvatarVersion makes a previous avatar current again.
+// Restore is recorded as a new version, so it can be undone too.
+func (s *Service) RestoreAvatarVersion(
+	ctx context.Context,
+	userId string,
+	chatId string,
+	versionId int64,
+) (models.Chat, error) {
+	const op = "service.RestoreAvatarVersion"
+
+	var chat models.Chat
+	err := s.storage.WithTx(ctx, func(ctx context.Context) error {
+		before, err := s.storage.LockChat(ctx, chatId)
+		if err != nil {
+			return err
+		}
+		if before.ChatOwnerId != userId {
+			return ErrPermissionDenied
+		}
+
+		version, err := s.storage.GetAvatarVersion(ctx, chatId, versionId)
+		if err != nil {
+			return err
+		}
+
+		// blob is kept alive by the old version, so nothing is uploaded
+		_, err = s.storage.AcquireAvatar(ctx, version.AvatarHash, 0)
+		if err != nil {
+			return err
+		}
+
+		url, expires, err := s.s3.GetAvatarUrl(ctx, version.AvatarHash)
+		if err != nil {
+			return err
+		}
+
+		chat, err = s.storage.UpdateChatInfo(ctx, userId, chatId, "", "", url, expires, version.AvatarHash, 0)
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, chatId, userId, models.AuditAvatarRestored, diffChats(before, chat))
+	})
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.cash.UpdateChat(ctx, chat)
+	if err != nil {
+		logger.GetFromCtx(ctx).Error(ctx, "failed to cache chat", zap.Error(err))
+	}
+
+	return chat, nil
+}
+
+// ListChatAuditLog returns audit entries of the chat, newest first.
+// Only chat owner can see it. Empty next page token means there are
+// no more entries.
+func (s *Service) ListChatAuditLog(
+	ctx context.Context,
+	userId string,
+	chatId string,
+	pageSize int,
+	pageToken string,
+) ([]models.AuditEntry, string, error) {
+	const op = "service.ListChatAuditLog"
+
+	chat, err := s.storage.GetChat(ctx, chatId)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	if chat.ChatOwnerId != userId {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+
+	if pageSize <= 0 {
+		pageSize = defaultAuditPageSize
+	}
+	pageSize = min(pageSize, maxAuditPageSize)
+
+	var beforeId int64
+	if pageToken != "" {
+		beforeId, err = strconv.ParseInt(pageToken, 10, 64)
+		if err != nil || beforeId <= 0 {
+			return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidPageToken)
+		}
+	}
+
+	// one extra entry tells whether there is a next page
+	entries, err := s.storage.GetAuditEntries(ctx, chatId, beforeId, pageSize+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var nextPageToken string
+	if len(entries) > pageSize {
+		entries = entries[:pageSize]
+		nextPageToken = strconv.FormatInt(entries[pageSize-1].ID, 10)
+	}
+
+	return entries, nextPageToken, nil
+}
+
+// RegisterWebhook subscribes url to events of the chat. Returned secret
+// signs deliveries and is not shown again.
+func (s *Service) RegisterWebhook(ctx context.Context, userId, chatId, rawUrl string) (models.Webhook, error) {
+	const op = "service.RegisterWebhook"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	parsed, err := url.Parse(rawUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, ErrInvalidWebhookUrl)
+	}
+
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var webhook models.Webhook
+	err = s.storage.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		webhook, err = s.storage.SaveWebhook(ctx, models.Webhook{
+			ID:        uuid.NewString(),
+			ChatId:    chatId,
+			Url:       parsed.String(),
+			Secret:    hex.EncodeToString(secret),
+			CreatedBy: userId,
+		})
 		if err != nil {
-			return fmt.Errorf("%s: %w", op, err)
+			return err
+		}
+
+		// secret stays out of the log
+		diff := map[string]models.AuditChange{
+			"webhook_id": {Before: nil, After: webhook.ID},
+			"url":        {Before: nil, After: webhook.Url},
 		}
+		return s.audit(ctx, chatId, userId, models.AuditWebhookAdded, diff)
+	})
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+// ListWebhooks returns webhooks of the chat without their secrets.
+func (s *Service) ListWebhooks(ctx context.Context, userId, chatId string) ([]models.Webhook, error) {
+	const op = "service.ListWebhooks"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	webhooks, err := s.storage.GetWebhooks(ctx, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+// TestWebhook sends test event to webhook and reports whether it was accepted.
+func (s *Service) TestWebhook(ctx context.Context, userId, chatId, webhookId string) error {
+	const op = "service.TestWebhook"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	webhook, err := s.storage.GetWebhook(ctx, chatId, webhookId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.webhooks.Send(ctx, webhook, models.Event{
+		ID:         uuid.NewString(),
+		Type:       models.EventWebhookTest,
+		Version:    models.EventSchemaVersion,
+		ChatId:     chatId,
+		OccurredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (s *Service) isImageExpire(expireTime time.Time) bool {
-	if expireTime.Compare(time.Now()) != 1 {
-		return false
+func (s *Service) DeleteWebhook(ctx context.Context, userId, chatId, webhookId string) error {
+	const op = "service.DeleteWebhook"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.storage.WithTx(ctx, func(ctx context.Context) error {
+		err := s.storage.DeleteWebhook(ctx, chatId, webhookId)
+		if err != nil {
+			return err
+		}
+
+		diff := map[string]models.AuditChange{"webhook_id": {Before: webhookId, After: nil}}
+		return s.audit(ctx, chatId, userId, models.AuditWebhookDeleted, diff)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
-	return true
+
+	return nil
 }
 
-func (s *Service) updateImageUrl(ctx context.Context, chatId string) (string, time.Time, error) {
-	const op = "service.updateImageUrl"
+// CreateInvite creates invite code for the chat. Zero ttl never expires,
+// zero max uses is unlimited.
+func (s *Service) CreateInvite(
+	ctx context.Context,
+	userId string,
+	chatId string,
+	ttl time.Duration,
+	maxUses int,
+) (models.Invite, error) {
+	const op = "service.CreateInvite"
+
+	if ttl < 0 || maxUses < 0 {
+		return models.Invite{}, fmt.Errorf("%s: %w", op, ErrInvalidInvite)
+	}
 
-	url, expireTime, err := s.s3.GetAvatarUrl(ctx, chatId)
+	err := s.checkOwner(ctx, userId, chatId)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+		return models.Invite{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = s.storage.UpdateImageUrl(ctx, chatId, url, expireTime)
+	code := make([]byte, 16)
+	_, err = rand.Read(code)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+		return models.Invite{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return url, expireTime, nil
+	invite := models.Invite{
+		Code:      base64.RawURLEncoding.EncodeToString(code),
+		ChatId:    chatId,
+		CreatedBy: userId,
+		MaxUses:   maxUses,
+	}
+	if ttl > 0 {
+		invite.ExpiresAt = time.Now().UTC().Add(ttl)
+	}
+
+	err = s.storage.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		invite, err = s.storage.SaveInvite(ctx, invite)
+		if err != nil {
+			return err
+		}
+
+		diff := map[string]models.AuditChange{
+			"invite":   {Before: nil, After: invite.Code},
+			"max_uses": {Before: nil, After: invite.MaxUses},
+		}
+		if !invite.ExpiresAt.IsZero() {
+			diff["expires_at"] = models.AuditChange{Before: nil, After: invite.ExpiresAt}
+		}
+		return s.audit(ctx, chatId, userId, models.AuditInviteCreated, diff)
+	})
+	if err != nil {
+		return models.Invite{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return invite, nil
+}
+
+// ListInvites returns invites of the chat that can still be used.
+func (s *Service) ListInvites(ctx context.Context, userId, chatId string) ([]models.Invite, error) {
+	const op = "service.ListInvites"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	invites, err := s.storage.GetInvites(ctx, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return invites, nil
+}
+
+func (s *Service) RevokeInvite(ctx context.Context, userId, chatId, code string) error {
+	const op = "service.RevokeInvite"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.storage.WithTx(ctx, func(ctx context.Context) error {
+		err := s.storage.RevokeInvite(ctx, chatId, code)
+		if err != nil {
+			return err
+		}
+
+		diff := map[string]models.AuditChange{"invite": {Before: code, After: nil}}
+		return s.audit(ctx, chatId, userId, models.AuditInviteRevoked, diff)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// JoinChatByInvite adds user to the chat of invite and uses invite once.
+// Participants joining again get ErrAlreadyJoined and the use is not counted.
+func (s *Service) JoinChatByInvite(ctx context.Context, userId, code string) (models.Chat, error) {
+	const op = "service.JoinChatByInvite"
+
+	var chat models.Chat
+	err := s.storage.WithTx(ctx, func(ctx context.Context) error {
+		chatId, err := s.storage.ConsumeInvite(ctx, code)
+		if err != nil {
+			return err
+		}
+
+		chat, err = s.join(ctx, chatId, userId)
+		return err
+	})
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.cash.UpdateChat(ctx, chat)
+	if err != nil {
+		logger.GetFromCtx(ctx).Error(ctx, "failed to cache chat", zap.Error(err))
+	}
+
+	return chat, nil
+}
+
+// RequestToJoin asks chat owner to add user to the chat.
+func (s *Service) RequestToJoin(ctx context.Context, userId, chatId, message string) (models.JoinRequest, error) {
+	const op = "service.RequestToJoin"
+
+	chat, err := s.storage.GetChat(ctx, chatId)
+	if err != nil {
+		return models.JoinRequest{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if chat.Type == models.ChatTypeDirect {
+		return models.JoinRequest{}, fmt.Errorf("%s: %w", op, ErrDirectChat)
+	}
+	member, err := s.isMember(ctx, chat, userId)
+	if err != nil {
+		return models.JoinRequest{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if member {
+		return models.JoinRequest{}, fmt.Errorf("%s: %w", op, ErrAlreadyJoined)
+	}
+
+	err = s.checkNotBanned(ctx, chatId, userId)
+	if err != nil {
+		return models.JoinRequest{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var request models.JoinRequest
+	err = s.storage.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		request, err = s.storage.SaveJoinRequest(ctx, models.JoinRequest{
+			ID:      uuid.NewString(),
+			ChatId:  chatId,
+			UserId:  userId,
+			Message: message,
+		})
+		if err != nil {
+			return err
+		}
+
+		diff := map[string]models.AuditChange{"join_request": {Before: nil, After: request.ID}}
+		return s.audit(ctx, chatId, userId, models.AuditJoinRequested, diff)
+	})
+	if err != nil {
+		return models.JoinRequest{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return request, nil
+}
+
+func (s *Service) ListJoinRequests(ctx context.Context, userId, chatId string) ([]models.JoinRequest, error) {
+	const op = "service.ListJoinRequests"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	requests, err := s.storage.GetPendingJoinRequests(ctx, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return requests, nil
+}
+
+// ApproveJoinRequest adds requester to the chat the same way AddParticipant does.
+func (s *Service) ApproveJoinRequest(ctx context.Context, userId, chatId, requestId string) (models.Chat, error) {
+	const op = "service.ApproveJoinRequest"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var chat models.Chat
+	err = s.storage.WithTx(ctx, func(ctx context.Context) error {
+		request, err := s.storage.DecideJoinRequest(ctx, chatId, requestId, models.JoinRequestApproved, userId)
+		if err != nil {
+			return err
+		}
+
+		chat, err = s.addParticipant(ctx, userId, chatId, request.UserId)
+		return err
+	})
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.cash.UpdateChat(ctx, chat)
+	if err != nil {
+		logger.GetFromCtx(ctx).Error(ctx, "failed to cache chat", zap.Error(err))
+	}
+
+	return chat, nil
+}
+
+func (s *Service) RejectJoinRequest(ctx context.Context, userId, chatId, requestId string) error {
+	const op = "service.RejectJoinRequest"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.storage.WithTx(ctx, func(ctx context.Context) error {
+		request, err := s.storage.DecideJoinRequest(ctx, chatId, requestId, models.JoinRequestRejected, userId)
+		if err != nil {
+			return err
+		}
+
+		diff := map[string]models.AuditChange{"rejected": {Before: nil, After: request.UserId}}
+		return s.audit(ctx, chatId, userId, models.AuditJoinRejected, diff)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// BanUser removes user from the chat and blocks adding them back.
+func (s *Service) BanUser(ctx context.Context, userId, chatId, bannedId, reason string) error {
+	const op = "service.BanUser"
+
+	var chat models.Chat
+	err := s.storage.WithTx(ctx, func(ctx context.Context) error {
+		before, err := s.storage.LockChat(ctx, chatId)
+		if err != nil {
+			return err
+		}
+		if before.ChatOwnerId != userId {
+			return ErrPermissionDenied
+		}
+		if bannedId == before.ChatOwnerId {
+			return ErrInvalidModeration
+		}
+
+		chat, err = s.storage.BanUser(ctx, models.Ban{
+			ChatId:   chatId,
+			UserId:   bannedId,
+			Reason:   reason,
+			BannedBy: userId,
+		})
+		if err != nil {
+			return err
+		}
+
+		diff := diffChats(before, chat)
+		diff["banned"] = models.AuditChange{Before: nil, After: bannedId}
+		return s.audit(ctx, chatId, userId, models.AuditUserBanned, diff)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.cash.UpdateChat(ctx, chat)
+	if err != nil {
+		logger.GetFromCtx(ctx).Error(ctx, "failed to cache chat", zap.Error(err))
+	}
+
+	return nil
+}
+
+// UnbanUser lifts the ban, user is not added back to the chat.
+func (s *Service) UnbanUser(ctx context.Context, userId, chatId, bannedId string) error {
+	const op = "service.UnbanUser"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.storage.WithTx(ctx, func(ctx context.Context) error {
+		err := s.storage.UnbanUser(ctx, chatId, bannedId, userId)
+		if err != nil {
+			return err
+		}
+
+		diff := map[string]models.AuditChange{"banned": {Before: bannedId, After: nil}}
+		return s.audit(ctx, chatId, userId, models.AuditUserUnbanned, diff)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Service) ListBans(ctx context.Context, userId, chatId string) ([]models.Ban, error) {
+	const op = "service.ListBans"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	bans, err := s.storage.GetBans(ctx, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bans, nil
+}
+
+// MuteUser stops participant from posting until given time.
+func (s *Service) MuteUser(
+	ctx context.Context,
+	userId string,
+	chatId string,
+	mutedId string,
+	reason string,
+	until time.Time,
+) (models.Mute, error) {
+	const op = "service.MuteUser"
+
+	chat, err := s.storage.GetChat(ctx, chatId)
+	if err != nil {
+		return models.Mute{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if chat.ChatOwnerId != userId {
+		return models.Mute{}, fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+	if mutedId == chat.ChatOwnerId || !until.After(time.Now()) {
+		return models.Mute{}, fmt.Errorf("%s: %w", op, ErrInvalidModeration)
+	}
+
+	var mute models.Mute
+	err = s.storage.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		mute, err = s.storage.MuteUser(ctx, models.Mute{
+			ChatId:  chatId,
+			UserId:  mutedId,
+			Reason:  reason,
+			MutedBy: userId,
+			Until:   until.UTC(),
+		})
+		if err != nil {
+			return err
+		}
+
+		diff := map[string]models.AuditChange{"muted_until": {Before: nil, After: mute.Until}}
+		return s.audit(ctx, chatId, userId, models.AuditUserMuted, diff)
+	})
+	if err != nil {
+		return models.Mute{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return mute, nil
+}
+
+func (s *Service) UnmuteUser(ctx context.Context, userId, chatId, mutedId string) error {
+	const op = "service.UnmuteUser"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.storage.WithTx(ctx, func(ctx context.Context) error {
+		err := s.storage.UnmuteUser(ctx, chatId, mutedId, userId)
+		if err != nil {
+			return err
+		}
+
+		diff := map[string]models.AuditChange{"muted": {Before: mutedId, After: nil}}
+		return s.audit(ctx, chatId, userId, models.AuditUserUnmuted, diff)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListMutes returns mutes of the chat that are still active.
+func (s *Service) ListMutes(ctx context.Context, userId, chatId string) ([]models.Mute, error) {
+	const op = "service.ListMutes"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	mutes, err := s.storage.GetMutes(ctx, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return mutes, nil
+}
+
+// CanPost returns nil when user may post to the chat, ErrPermissionDenied
+// for non participants and channel subscribers and ErrUserMuted while
+// user is muted.
+func (s *Service) CanPost(ctx context.Context, userId, chatId string) error {
+	const op = "service.CanPost"
+
+	chat, err := s.GetChat(ctx, chatId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// channel participants are its admins
+	if !slices.Contains(chat.ParticipantsId, userId) {
+		return fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+
+	mute, err := s.storage.GetMute(ctx, chatId, userId)
+	if err != nil {
+		if errors.Is(err, storage.ErrMuteNotFound) {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return fmt.Errorf("%s: %w until %s", op, ErrUserMuted, mute.Until.Format(time.RFC3339))
+}
+
+// CanReact returns nil when user may react to messages of the chat,
+// channel subscribers can react but not post.
+func (s *Service) CanReact(ctx context.Context, userId, chatId string) error {
+	const op = "service.CanReact"
+
+	chat, err := s.GetChat(ctx, chatId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	member, err := s.isMember(ctx, chat, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !member {
+		return fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+
+	return nil
+}
+
+// ListSubscribers returns page of channel subscribers after given user id
+// and token of the next page, empty when there are no more subscribers.
+func (s *Service) ListSubscribers(
+	ctx context.Context,
+	userId string,
+	chatId string,
+	pageSize int,
+	pageToken string,
+) ([]string, string, error) {
+	const op = "service.ListSubscribers"
+
+	err := s.checkOwner(ctx, userId, chatId)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if pageSize <= 0 {
+		pageSize = defaultSubscribersPageSize
+	}
+	pageSize = min(pageSize, maxSubscribersPageSize)
+
+	subscribers, err := s.storage.GetSubscribers(ctx, chatId, pageToken, pageSize+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var nextPageToken string
+	if len(subscribers) > pageSize {
+		subscribers = subscribers[:pageSize]
+		nextPageToken = subscribers[pageSize-1]
+	}
+
+	return subscribers, nextPageToken, nil
+}
+
+// SetChatVisibility lists chat in public directory or removes it from there.
+func (s *Service) SetChatVisibility(ctx context.Context, userId, chatId string, public bool) (models.Chat, error) {
+	const op = "service.SetChatVisibility"
+
+	var chat models.Chat
+	err := s.storage.WithTx(ctx, func(ctx context.Context) error {
+		before, err := s.storage.LockChat(ctx, chatId)
+		if err != nil {
+			return err
+		}
+		if before.ChatOwnerId != userId {
+			return ErrPermissionDenied
+		}
+		if before.Type == models.ChatTypeDirect {
+			return ErrDirectChat
+		}
+
+		chat, err = s.storage.SetChatVisibility(ctx, userId, chatId, public)
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, chatId, userId, models.AuditChatUpdated, diffChats(before, chat))
+	})
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.cash.UpdateChat(ctx, chat)
+	if err != nil {
+		logger.GetFromCtx(ctx).Error(ctx, "failed to cache chat", zap.Error(err))
+	}
+
+	return chat, nil
+}
+
+// SearchPublicChats searches public chats by name and description.
+// Page token is offset of the next page.
+func (s *Service) SearchPublicChats(
+	ctx context.Context,
+	query string,
+	pageSize int,
+	pageToken string,
+) ([]models.PublicChat, string, error) {
+	const op = "service.SearchPublicChats"
+
+	if strings.TrimSpace(query) == "" {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrEmptySearchQuery)
+	}
+
+	if pageSize <= 0 {
+		pageSize = defaultSearchPageSize
+	}
+	pageSize = min(pageSize, maxSearchPageSize)
+
+	var offset int
+	if pageToken != "" {
+		var err error
+		offset, err = strconv.Atoi(pageToken)
+		if err != nil || offset <= 0 {
+			return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidPageToken)
+		}
+	}
+
+	// one extra chat tells whether there is a next page
+	chats, err := s.storage.SearchPublicChats(ctx, query, offset, pageSize+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var nextPageToken string
+	if len(chats) > pageSize {
+		chats = chats[:pageSize]
+		nextPageToken = strconv.Itoa(offset + pageSize)
+	}
+
+	for i := range chats {
+		if s.isImageExpire(chats[i].ImageExpireTime) {
+			chats[i].ChatImageUrl, chats[i].ImageExpireTime, err = s.updateImageUrl(ctx, chats[i].ID, chats[i].AvatarHash)
+			if err != nil {
+				return nil, "", fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	return chats, nextPageToken, nil
+}
+
+// JoinPublicChat adds user to public chat without invite.
+func (s *Service) JoinPublicChat(ctx context.Context, userId, chatId string) (models.Chat, error) {
+	const op = "service.JoinPublicChat"
+
+	var chat models.Chat
+	err := s.storage.WithTx(ctx, func(ctx context.Context) error {
+		before, err := s.storage.LockChat(ctx, chatId)
+		if err != nil {
+			return err
+		}
+		if !before.Public {
+			return ErrPermissionDenied
+		}
+
+		chat, err = s.join(ctx, chatId, userId)
+		return err
+	})
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.cash.UpdateChat(ctx, chat)
+	if err != nil {
+		logger.GetFromCtx(ctx).Error(ctx, "failed to cache chat", zap.Error(err))
+	}
+
+	return chat, nil
+}
+
+// checkOwner returns ErrPermissionDenied unless user owns the chat.
+func (s *Service) checkOwner(ctx context.Context, userId, chatId string) error {
+	const op = "service.checkOwner"
+
+	chat, err := s.storage.GetChat(ctx, chatId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if chat.ChatOwnerId != userId {
+		return fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+
+	return nil
+}
+
+// addParticipant adds participant on behalf of chat owner and records it
+// in audit log. Adding a member again leaves the chat as it is.
+// It must run in transaction.
+func (s *Service) addParticipant(ctx context.Context, userId, chatId, participantId string) (models.Chat, error) {
+	const op = "service.addParticipant"
+
+	before, err := s.storage.LockChat(ctx, chatId)
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if before.ChatOwnerId != userId {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, ErrPermissionDenied)
+	}
+
+	member, err := s.isMember(ctx, before, participantId)
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if member {
+		return before, nil
+	}
+
+	err = s.checkNotBanned(ctx, chatId, participantId)
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var chat models.Chat
+	if before.Type == models.ChatTypeChannel {
+		chat, err = s.storage.Subscribe(ctx, chatId, participantId, userId)
+	} else {
+		chat, err = s.storage.AddParticipant(ctx, userId, chatId, participantId)
+	}
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.audit(ctx, chatId, userId, models.AuditParticipantAdded, diffMembers(before, chat, participantId))
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chat, nil
+}
+
+// join adds user to the chat on their own behalf and records it
+// in audit log. It must run in transaction.
+func (s *Service) join(ctx context.Context, chatId, userId string) (models.Chat, error) {
+	const op = "service.join"
+
+	before, err := s.storage.LockChat(ctx, chatId)
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	member, err := s.isMember(ctx, before, userId)
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if member {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, ErrAlreadyJoined)
+	}
+
+	err = s.checkNotBanned(ctx, chatId, userId)
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var chat models.Chat
+	if before.Type == models.ChatTypeChannel {
+		chat, err = s.storage.Subscribe(ctx, chatId, userId, userId)
+	} else {
+		chat, err = s.storage.JoinChat(ctx, chatId, userId, userId)
+	}
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.audit(ctx, chatId, userId, models.AuditParticipantAdded, diffMembers(before, chat, userId))
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chat, nil
+}
+
+// isMember reports whether user is participant of the chat
+// or subscriber of the channel.
+func (s *Service) isMember(ctx context.Context, chat models.Chat, userId string) (bool, error) {
+	const op = "service.isMember"
+
+	if slices.Contains(chat.ParticipantsId, userId) {
+		return true, nil
+	}
+	if chat.Type != models.ChatTypeChannel {
+		return false, nil
+	}
+
+	subscribed, err := s.storage.IsSubscribed(ctx, chat.ID, userId)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return subscribed, nil
+}
+
+// checkNotBanned returns ErrUserBanned if user is banned in the chat.
+func (s *Service) checkNotBanned(ctx context.Context, chatId, userId string) error {
+	const op = "service.checkNotBanned"
+
+	banned, err := s.storage.IsBanned(ctx, chatId, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if banned {
+		return fmt.Errorf("%s: %w", op, ErrUserBanned)
+	}
+
+	return nil
+}
+
+// isImageExpire reports whether presigned image url must be refreshed.
+// Zero expire time is used for stable urls that never expire.
+func (s *Service) isImageExpire(expireTime time.Time) bool {
+	if expireTime.IsZero() {
+		return false
+	}
+	return !time.Now().Before(expireTime)
+}
+
+func (s *Service) updateImageUrl(ctx context.Context, chatId, avatarHash string) (string, time.Time, error) {
+	const op = "service.updateImageUrl"
+
+	url, expireTime, err := s.s3.GetAvatarUrl(ctx, avatarHash)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.storage.UpdateImageUrl(ctx, chatId, url, expireTime)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, expireTime, nil
+}
+
+// acquireAvatar stores avatar under its content hash, so identical images
+// share one object. Blob is uploaded only for the first reference.
+// It must run in a transaction: reference is dropped on rollback, and
+// concurrent uploads of the same content wait for the first one to finish.
+// Blob uploaded by a rolled back transaction is collected by gc.
+func (s *Service) acquireAvatar(ctx context.Context, data []byte) (string, string, time.Time, error) {
+	const op = "service.acquireAvatar"
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	created, err := s.storage.AcquireAvatar(ctx, hash, int64(len(data)))
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var url string
+	var expires time.Time
+	if created {
+		url, expires, err = s.s3.SaveAvatar(ctx, &models.Avatar{
+			ID:   hash,
+			Data: data,
+		})
+	} else {
+		url, expires, err = s.s3.GetAvatarUrl(ctx, hash)
+	}
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hash, url, expires, nil
+}
+
+// audit records mutation made by actor. It must run in the transaction
+// of the mutation, so the log never misses or invents a change.
+func (s *Service) audit(ctx context.Context, chatId, actorId, action string, diff map[string]models.AuditChange) error {
+	requestId, _ := ctx.Value(logger.RequestID).(string)
+	if diff == nil {
+		diff = map[string]models.AuditChange{}
+	}
+
+	return s.storage.SaveAuditEntry(ctx, models.AuditEntry{
+		ChatId:    chatId,
+		ActorId:   actorId,
+		Action:    action,
+		Diff:      diff,
+		RequestId: requestId,
+	})
+}
+
+// diffMembers is diffChats of added member, channel subscribers are not
+// part of chat, so they are recorded separately.
+func diffMembers(before, after models.Chat, userId string) map[string]models.AuditChange {
+	diff := diffChats(before, after)
+	if after.Type == models.ChatTypeChannel && after.SubscribersCount != before.SubscribersCount {
+		diff["subscribed"] = models.AuditChange{Before: nil, After: userId}
+	}
+
+	return diff
+}
+
+// diffChats returns user visible fields that differ between chats.
+func diffChats(before, after models.Chat) map[string]models.AuditChange {
+	diff := map[string]models.AuditChange{}
+
+	add := func(field string, before, after any, changed bool) {
+		if changed {
+			diff[field] = models.AuditChange{Before: before, After: after}
+		}
+	}
+
+	add("name", before.Name, after.Name, before.Name != after.Name)
+	add("description", before.Description, after.Description, before.Description != after.Description)
+	add("avatar_hash", before.AvatarHash, after.AvatarHash, before.AvatarHash != after.AvatarHash)
+	add("owner_id", before.ChatOwnerId, after.ChatOwnerId, before.ChatOwnerId != after.ChatOwnerId)
+	add("public", before.Public, after.Public, before.Public != after.Public)
+	add(
+		"participants_id",
+		before.ParticipantsId,
+		after.ParticipantsId,
+		!slices.Equal(before.ParticipantsId, after.ParticipantsId),
+	)
+
+	return diff
 }
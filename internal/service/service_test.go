@@ -0,0 +1,601 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/internal/storage"
+	"github.com/AlexMickh/speak-chat/pkg/logger"
+)
+
+func Test_diffChats(t *testing.T) {
+	chat := models.Chat{
+		ID:             "chat",
+		Name:           "name",
+		Description:    "description",
+		ChatImageUrl:   "url",
+		AvatarHash:     "hash",
+		ChatOwnerId:    "owner",
+		Version:        1,
+		ParticipantsId: []string{"owner"},
+	}
+
+	renamed := chat
+	renamed.Name = "renamed"
+	renamed.Version = 2
+
+	joined := chat
+	joined.ParticipantsId = []string{"owner", "user"}
+
+	refreshed := chat
+	refreshed.ChatImageUrl = "new url"
+
+	tests := []struct {
+		name   string
+		before models.Chat
+		after  models.Chat
+		want   map[string]models.AuditChange
+	}{
+		{
+			name:   "rename",
+			before: chat,
+			after:  renamed,
+			want: map[string]models.AuditChange{
+				"name": {Before: "name", After: "renamed"},
+			},
+		},
+		{
+			name:   "participant added",
+			before: chat,
+			after:  joined,
+			want: map[string]models.AuditChange{
+				"participants_id": {Before: []string{"owner"}, After: []string{"owner", "user"}},
+			},
+		},
+		{
+			name:   "url refresh is not a change",
+			before: chat,
+			after:  refreshed,
+			want:   map[string]models.AuditChange{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffChats(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffChats() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_diffMembers(t *testing.T) {
+	channel := models.Chat{
+		ID:             "channel",
+		ChatOwnerId:    "owner",
+		Type:           models.ChatTypeChannel,
+		ParticipantsId: []string{"owner"},
+	}
+
+	subscribed := channel
+	subscribed.SubscribersCount = 1
+
+	group := channel
+	group.Type = models.ChatTypeGroup
+
+	joined := group
+	joined.ParticipantsId = []string{"owner", "user"}
+
+	tests := []struct {
+		name   string
+		before models.Chat
+		after  models.Chat
+		want   map[string]models.AuditChange
+	}{
+		{
+			name:   "channel subscriber",
+			before: channel,
+			after:  subscribed,
+			want: map[string]models.AuditChange{
+				"subscribed": {Before: nil, After: "user"},
+			},
+		},
+		{
+			name:   "group participant",
+			before: group,
+			after:  joined,
+			want: map[string]models.AuditChange{
+				"participants_id": {Before: []string{"owner"}, After: []string{"owner", "user"}},
+			},
+		},
+		{
+			name:   "already subscribed",
+			before: subscribed,
+			after:  subscribed,
+			want:   map[string]models.AuditChange{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffMembers(tt.before, tt.after, "user"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffMembers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+var errMissingBlob = errors.New("blob does not exist")
+
+// fakeStorage implements what avatar versions and participants need,
+// other methods panic.
+type fakeStorage struct {
+	Storage
+	chat     models.Chat
+	versions []models.AvatarVersion
+	acquired []string
+	webhooks []models.Webhook
+	invites  []string
+	requests []models.JoinRequest
+	audited  []string
+	diffs    []map[string]models.AuditChange
+}
+
+func (f *fakeStorage) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (f *fakeStorage) GetChat(ctx context.Context, id string) (models.Chat, error) {
+	if id != f.chat.ID {
+		return models.Chat{}, storage.ErrChatNotFound
+	}
+	return f.chat, nil
+}
+
+func (f *fakeStorage) LockChat(ctx context.Context, id string) (models.Chat, error) {
+	return f.GetChat(ctx, id)
+}
+
+func (f *fakeStorage) GetAvatarVersions(ctx context.Context, chatId string) ([]models.AvatarVersion, error) {
+	return slices.Clone(f.versions), nil
+}
+
+func (f *fakeStorage) GetAvatarVersion(ctx context.Context, chatId string, versionId int64) (models.AvatarVersion, error) {
+	for _, version := range f.versions {
+		if version.ID == versionId && version.ChatId == chatId {
+			return version, nil
+		}
+	}
+	return models.AvatarVersion{}, storage.ErrAvatarVersionNotFound
+}
+
+func (f *fakeStorage) AcquireAvatar(ctx context.Context, hash string, size int64) (bool, error) {
+	f.acquired = append(f.acquired, hash)
+	return false, nil
+}
+
+func (f *fakeStorage) UpdateChatInfo(
+	ctx context.Context,
+	userId string,
+	chatId string,
+	name string,
+	description string,
+	chatImageUrl string,
+	imageExireTime time.Time,
+	avatarHash string,
+	expectedVersion int64,
+) (models.Chat, error) {
+	if userId != f.chat.ChatOwnerId {
+		return models.Chat{}, storage.ErrChatNotFound
+	}
+	f.chat.AvatarHash = avatarHash
+	f.chat.ChatImageUrl = chatImageUrl
+	f.chat.Version++
+	return f.chat, nil
+}
+
+func (f *fakeStorage) IsBanned(ctx context.Context, chatId, userId string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeStorage) AddParticipant(ctx context.Context, userId, chatId, participantId string) (models.Chat, error) {
+	f.chat.ParticipantsId = append(slices.Clone(f.chat.ParticipantsId), participantId)
+	f.chat.Version++
+	return f.chat, nil
+}
+
+func (f *fakeStorage) SaveWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	f.webhooks = append(f.webhooks, webhook)
+	return webhook, nil
+}
+
+func (f *fakeStorage) DeleteWebhook(ctx context.Context, chatId, webhookId string) error {
+	for i, webhook := range f.webhooks {
+		if webhook.ID == webhookId && webhook.ChatId == chatId {
+			f.webhooks = slices.Delete(f.webhooks, i, i+1)
+			return nil
+		}
+	}
+	return storage.ErrWebhookNotFound
+}
+
+func (f *fakeStorage) SaveInvite(ctx context.Context, invite models.Invite) (models.Invite, error) {
+	f.invites = append(f.invites, invite.Code)
+	return invite, nil
+}
+
+func (f *fakeStorage) RevokeInvite(ctx context.Context, chatId, code string) error {
+	if !slices.Contains(f.invites, code) {
+		return storage.ErrInviteNotFound
+	}
+	f.invites = slices.DeleteFunc(f.invites, func(invite string) bool { return invite == code })
+	return nil
+}
+
+func (f *fakeStorage) IsSubscribed(ctx context.Context, chatId, userId string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeStorage) SaveJoinRequest(ctx context.Context, request models.JoinRequest) (models.JoinRequest, error) {
+	request.Status = models.JoinRequestPending
+	f.requests = append(f.requests, request)
+	return request, nil
+}
+
+func (f *fakeStorage) DecideJoinRequest(
+	ctx context.Context,
+	chatId string,
+	requestId string,
+	status string,
+	decidedBy string,
+) (models.JoinRequest, error) {
+	for i, request := range f.requests {
+		if request.ID == requestId && request.ChatId == chatId && request.Status == models.JoinRequestPending {
+			f.requests[i].Status = status
+			return f.requests[i], nil
+		}
+	}
+	return models.JoinRequest{}, storage.ErrJoinRequestNotFound
+}
+
+func (f *fakeStorage) SaveAuditEntry(ctx context.Context, entry models.AuditEntry) error {
+	f.audited = append(f.audited, entry.Action)
+	f.diffs = append(f.diffs, entry.Diff)
+	return nil
+}
+
+type fakeS3 struct {
+	S3
+	blobs []string
+}
+
+func (f *fakeS3) GetAvatarUrl(ctx context.Context, avatarId string) (string, time.Time, error) {
+	if !slices.Contains(f.blobs, avatarId) {
+		return "", time.Time{}, errMissingBlob
+	}
+	return "https://cdn.example.com/" + avatarId, time.Time{}, nil
+}
+
+type fakeCash struct {
+	Cash
+}
+
+func (f *fakeCash) UpdateChat(ctx context.Context, chat models.Chat) error {
+	return nil
+}
+
+func newAvatarFakes() (*fakeStorage, *fakeS3) {
+	store := &fakeStorage{
+		chat: models.Chat{ID: "chat", ChatOwnerId: "owner", AvatarHash: "second", Version: 2},
+		versions: []models.AvatarVersion{
+			{ID: 2, ChatId: "chat", AvatarHash: "second"},
+			{ID: 1, ChatId: "chat", AvatarHash: "first"},
+		},
+	}
+	return store, &fakeS3{blobs: []string{"first", "second"}}
+}
+
+func TestService_ListAvatarVersions(t *testing.T) {
+	ctx := logger.New(context.Background(), []string{"stderr"}, "prod")
+
+	tests := []struct {
+		name     string
+		userId   string
+		wantUrls []string
+		wantErr  error
+	}{
+		{
+			name:     "owner sees versions with urls",
+			userId:   "owner",
+			wantUrls: []string{"https://cdn.example.com/second", "https://cdn.example.com/first"},
+		},
+		{
+			name:    "stranger is denied",
+			userId:  "stranger",
+			wantErr: ErrPermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, s3 := newAvatarFakes()
+			s := New(store, &fakeCash{}, s3, nil, time.Hour)
+
+			versions, err := s.ListAvatarVersions(ctx, tt.userId, "chat")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Service.ListAvatarVersions() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var urls []string
+			for _, version := range versions {
+				urls = append(urls, version.ImageUrl)
+			}
+			if !slices.Equal(urls, tt.wantUrls) {
+				t.Errorf("Service.ListAvatarVersions() urls = %v, want %v", urls, tt.wantUrls)
+			}
+		})
+	}
+}
+
+func TestService_RestoreAvatarVersion(t *testing.T) {
+	ctx := logger.New(context.Background(), []string{"stderr"}, "prod")
+
+	tests := []struct {
+		name      string
+		userId    string
+		versionId int64
+		// collected blobs are removed from s3 before restore
+		collected []string
+		wantHash  string
+		wantErr   error
+	}{
+		{
+			name:      "previous version becomes current",
+			userId:    "owner",
+			versionId: 1,
+			wantHash:  "first",
+		},
+		{
+			name:      "unknown version",
+			userId:    "owner",
+			versionId: 42,
+			wantErr:   storage.ErrAvatarVersionNotFound,
+		},
+		{
+			name:      "stranger is denied",
+			userId:    "stranger",
+			versionId: 1,
+			wantErr:   ErrPermissionDenied,
+		},
+		{
+			name:      "blob removed by gc is not restored",
+			userId:    "owner",
+			versionId: 1,
+			collected: []string{"first"},
+			wantErr:   errMissingBlob,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, s3 := newAvatarFakes()
+			s3.blobs = slices.DeleteFunc(s3.blobs, func(blob string) bool {
+				return slices.Contains(tt.collected, blob)
+			})
+			s := New(store, &fakeCash{}, s3, nil, time.Hour)
+
+			chat, err := s.RestoreAvatarVersion(ctx, tt.userId, "chat", tt.versionId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Service.RestoreAvatarVersion() error = %v, want %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrPermissionDenied) && len(store.acquired) != 0 {
+				t.Errorf("acquired avatars = %v, want none", store.acquired)
+			}
+			if err != nil {
+				return
+			}
+
+			if chat.AvatarHash != tt.wantHash {
+				t.Errorf("Service.RestoreAvatarVersion() hash = %v, want %v", chat.AvatarHash, tt.wantHash)
+			}
+			if !slices.Equal(store.acquired, []string{tt.wantHash}) {
+				t.Errorf("acquired avatars = %v, want %v", store.acquired, []string{tt.wantHash})
+			}
+			if !slices.Equal(store.audited, []string{models.AuditAvatarRestored}) {
+				t.Errorf("audited actions = %v, want %v", store.audited, []string{models.AuditAvatarRestored})
+			}
+		})
+	}
+}
+
+func TestService_AddParticipant(t *testing.T) {
+	ctx := logger.New(context.Background(), []string{"stderr"}, "prod")
+
+	tests := []struct {
+		name             string
+		userId           string
+		participantId    string
+		wantParticipants []string
+		wantAudited      []string
+		wantErr          error
+	}{
+		{
+			name:             "owner adds user",
+			userId:           "owner",
+			participantId:    "user",
+			wantParticipants: []string{"owner", "member", "user"},
+			wantAudited:      []string{models.AuditParticipantAdded},
+		},
+		{
+			name:             "member is not added twice",
+			userId:           "owner",
+			participantId:    "member",
+			wantParticipants: []string{"owner", "member"},
+		},
+		{
+			name:             "member can not add users",
+			userId:           "member",
+			participantId:    "user",
+			wantParticipants: []string{"owner", "member"},
+			wantErr:          ErrPermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStorage{
+				chat: models.Chat{
+					ID:             "chat",
+					ChatOwnerId:    "owner",
+					Type:           models.ChatTypeGroup,
+					ParticipantsId: []string{"owner", "member"},
+				},
+			}
+			s := New(store, &fakeCash{}, &fakeS3{}, nil, time.Hour)
+
+			err := s.AddParticipant(ctx, tt.userId, "chat", tt.participantId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Service.AddParticipant() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !slices.Equal(store.chat.ParticipantsId, tt.wantParticipants) {
+				t.Errorf("participants = %v, want %v", store.chat.ParticipantsId, tt.wantParticipants)
+			}
+			if !slices.Equal(store.audited, tt.wantAudited) {
+				t.Errorf("audited actions = %v, want %v", store.audited, tt.wantAudited)
+			}
+		})
+	}
+}
+
+func TestService_UpdateChatInfo(t *testing.T) {
+	ctx := logger.New(context.Background(), []string{"stderr"}, "prod")
+
+	tests := []struct {
+		name        string
+		userId      string
+		wantVersion int64
+		wantAudited []string
+		wantErr     error
+	}{
+		{
+			name:        "owner updates chat",
+			userId:      "owner",
+			wantVersion: 3,
+			wantAudited: []string{models.AuditChatUpdated},
+		},
+		{
+			name:        "stranger is denied",
+			userId:      "stranger",
+			wantVersion: 2,
+			wantErr:     ErrPermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, s3 := newAvatarFakes()
+			s := New(store, &fakeCash{}, s3, nil, time.Hour)
+
+			_, err := s.UpdateChatInfo(ctx, tt.userId, "chat", "renamed", "", nil, 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Service.UpdateChatInfo() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if store.chat.Version != tt.wantVersion {
+				t.Errorf("chat version = %v, want %v", store.chat.Version, tt.wantVersion)
+			}
+			if !slices.Equal(store.audited, tt.wantAudited) {
+				t.Errorf("audited actions = %v, want %v", store.audited, tt.wantAudited)
+			}
+		})
+	}
+}
+
+func TestService_Webhooks(t *testing.T) {
+	ctx := logger.New(context.Background(), []string{"stderr"}, "prod")
+	store, s3 := newAvatarFakes()
+	s := New(store, &fakeCash{}, s3, nil, time.Hour)
+
+	_, err := s.RegisterWebhook(ctx, "stranger", "chat", "https://hooks.example.com")
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("Service.RegisterWebhook() error = %v, want %v", err, ErrPermissionDenied)
+	}
+
+	webhook, err := s.RegisterWebhook(ctx, "owner", "chat", "https://hooks.example.com")
+	if err != nil {
+		t.Fatalf("Service.RegisterWebhook() error = %v", err)
+	}
+	for _, change := range store.diffs[0] {
+		if change.After == webhook.Secret {
+			t.Errorf("audit diff %v contains webhook secret", store.diffs[0])
+		}
+	}
+
+	err = s.DeleteWebhook(ctx, "owner", "chat", webhook.ID)
+	if err != nil {
+		t.Fatalf("Service.DeleteWebhook() error = %v", err)
+	}
+
+	want := []string{models.AuditWebhookAdded, models.AuditWebhookDeleted}
+	if !slices.Equal(store.audited, want) {
+		t.Errorf("audited actions = %v, want %v", store.audited, want)
+	}
+}
+
+func TestService_Invites(t *testing.T) {
+	ctx := logger.New(context.Background(), []string{"stderr"}, "prod")
+	store, s3 := newAvatarFakes()
+	s := New(store, &fakeCash{}, s3, nil, time.Hour)
+
+	_, err := s.CreateInvite(ctx, "stranger", "chat", time.Hour, 1)
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("Service.CreateInvite() error = %v, want %v", err, ErrPermissionDenied)
+	}
+
+	invite, err := s.CreateInvite(ctx, "owner", "chat", 0, 5)
+	if err != nil {
+		t.Fatalf("Service.CreateInvite() error = %v", err)
+	}
+	if _, ok := store.diffs[0]["expires_at"]; ok {
+		t.Errorf("audit diff %v has expiry of invite that never expires", store.diffs[0])
+	}
+
+	err = s.RevokeInvite(ctx, "owner", "chat", invite.Code)
+	if err != nil {
+		t.Fatalf("Service.RevokeInvite() error = %v", err)
+	}
+
+	want := []string{models.AuditInviteCreated, models.AuditInviteRevoked}
+	if !slices.Equal(store.audited, want) {
+		t.Errorf("audited actions = %v, want %v", store.audited, want)
+	}
+}
+
+func TestService_JoinRequests(t *testing.T) {
+	ctx := logger.New(context.Background(), []string{"stderr"}, "prod")
+	store, s3 := newAvatarFakes()
+	s := New(store, &fakeCash{}, s3, nil, time.Hour)
+
+	request, err := s.RequestToJoin(ctx, "user", "chat", "hi")
+	if err != nil {
+		t.Fatalf("Service.RequestToJoin() error = %v", err)
+	}
+
+	err = s.RejectJoinRequest(ctx, "user", "chat", request.ID)
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("Service.RejectJoinRequest() error = %v, want %v", err, ErrPermissionDenied)
+	}
+
+	err = s.RejectJoinRequest(ctx, "owner", "chat", request.ID)
+	if err != nil {
+		t.Fatalf("Service.RejectJoinRequest() error = %v", err)
+	}
+
+	want := []string{models.AuditJoinRequested, models.AuditJoinRejected}
+	if !slices.Equal(store.audited, want) {
+		t.Errorf("audited actions = %v, want %v", store.audited, want)
+	}
+	if got := store.diffs[1]["rejected"].After; got != "user" {
+		t.Errorf("rejected user = %v, want %v", got, "user")
+	}
+}
@@ -0,0 +1,230 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+)
+
+const defaultImage = "avatar.png"
+
+var ErrInvalidKey = errors.New("invalid object key")
+
+// Filesystem stores avatars in a local directory and serves them over http
+// with HMAC-signed, expiring urls that mirror presigned url semantics.
+// It is meant for local development and CI without MinIO.
+type Filesystem struct {
+	dir     string
+	baseUrl string
+	secret  []byte
+	expires time.Duration
+}
+
+func New(dir string, baseUrl string, secret string, expires time.Duration) (*Filesystem, error) {
+	const op = "storage.filesystem.New"
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Filesystem{
+		dir:     dir,
+		baseUrl: strings.TrimRight(baseUrl, "/"),
+		secret:  []byte(secret),
+		expires: expires,
+	}, nil
+}
+
+func (f *Filesystem) SaveAvatar(ctx context.Context, avatar *models.Avatar) (string, time.Time, error) {
+	const op = "storage.filesystem.SaveAvatar"
+
+	if avatar == nil {
+		url, expires, err := f.GetAvatarUrl(ctx, defaultImage)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return url, expires, nil
+	}
+
+	path, err := f.path(avatar.ID)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// write to temp file first, so readers never see partial content
+	tmp, err := os.CreateTemp(f.dir, ".upload-*")
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(avatar.Data)
+	if err != nil {
+		tmp.Close()
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	url, expires, err := f.GetAvatarUrl(ctx, avatar.ID)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, expires, nil
+}
+
+func (f *Filesystem) GetAvatarUrl(ctx context.Context, avatarId string) (string, time.Time, error) {
+	const op = "storage.filesystem.GetAvatarUrl"
+
+	if avatarId == "" {
+		avatarId = defaultImage
+	}
+
+	if _, err := f.path(avatarId); err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	expires := time.Now().Add(f.expires)
+	expiresStr := strconv.FormatInt(expires.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expiresStr)
+	query.Set("signature", f.sign(avatarId, expiresStr))
+
+	return f.baseUrl + "/" + url.PathEscape(avatarId) + "?" + query.Encode(), expires, nil
+}
+
+func (f *Filesystem) DeleteAvatar(ctx context.Context, avatarId string) (string, time.Time, error) {
+	const op = "storage.filesystem.DeleteAvatar"
+
+	err := f.RemoveAvatar(ctx, avatarId)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	url, expires, err := f.GetAvatarUrl(ctx, defaultImage)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, expires, nil
+}
+
+// ListAvatars returns all stored objects except the default image.
+func (f *Filesystem) ListAvatars(ctx context.Context) ([]models.AvatarObject, error) {
+	const op = "storage.filesystem.ListAvatars"
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var objects []models.AvatarObject
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == defaultImage || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		objects = append(objects, models.AvatarObject{
+			Key:          entry.Name(),
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+	}
+
+	return objects, nil
+}
+
+func (f *Filesystem) RemoveAvatar(ctx context.Context, key string) error {
+	const op = "storage.filesystem.RemoveAvatar"
+
+	path, err := f.path(key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// removing missing object is not an error, same as in s3
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Handler serves stored avatars. Requests must carry a valid, not expired
+// signature produced by GetAvatarUrl.
+func (f *Filesystem) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		path, err := f.path(key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		expiresStr := r.URL.Query().Get("expires")
+		expires, err := strconv.ParseInt(expiresStr, 10, 64)
+		if err != nil || time.Now().Unix() > expires {
+			http.Error(w, "url expired", http.StatusForbidden)
+			return
+		}
+
+		signature := r.URL.Query().Get("signature")
+		if !hmac.Equal([]byte(signature), []byte(f.sign(key, expiresStr))) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		http.ServeFile(w, r, path)
+	})
+}
+
+func (f *Filesystem) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// path maps object key to a file inside storage directory.
+// Keys are flat, so anything that looks like a path is rejected.
+func (f *Filesystem) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(f.dir, key), nil
+}
@@ -0,0 +1,93 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+)
+
+func TestFilesystem_Handler(t *testing.T) {
+	f, err := New(t.TempDir(), "", "secret", time.Hour)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	srv := httptest.NewServer(f.Handler())
+	defer srv.Close()
+	f.baseUrl = srv.URL
+
+	avatar := &models.Avatar{
+		ID:   "hash",
+		Data: []byte("image"),
+	}
+	signed, _, err := f.SaveAvatar(context.Background(), avatar)
+	if err != nil {
+		t.Fatalf("Filesystem.SaveAvatar() error = %v", err)
+	}
+
+	expired, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("url.Parse() error = %v", err)
+	}
+	query := expired.Query()
+	query.Set("expires", "1")
+	query.Set("signature", f.sign("hash", "1"))
+	expired.RawQuery = query.Encode()
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "good case",
+			url:        signed,
+			wantStatus: http.StatusOK,
+			wantBody:   "image",
+		},
+		{
+			name:       "tampered signature",
+			url:        signed + "00",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "expired url",
+			url:        expired.String(),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "other object",
+			url:        strings.Replace(signed, "/hash?", "/other?", 1),
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(tt.url)
+			if err != nil {
+				t.Fatalf("http.Get() error = %v", err)
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if tt.wantBody == "" {
+				return
+			}
+
+			body, _ := io.ReadAll(res.Body)
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
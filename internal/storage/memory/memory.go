@@ -0,0 +1,250 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Cash is the shared cache tier behind the in-process one.
+type Cash interface {
+	SaveChat(ctx context.Context, chat models.Chat) error
+	GetChat(ctx context.Context, id string) (models.Chat, error)
+	SaveMissingChat(ctx context.Context, id string) error
+	UpdateChat(ctx context.Context, chat models.Chat) error
+	DeleteChat(ctx context.Context, chatId string) error
+}
+
+type Invalidator interface {
+	PublishInvalidation(ctx context.Context, chatId string) error
+	ListenInvalidations(ctx context.Context, fn func(chatId string)) error
+}
+
+type entry struct {
+	chat      models.Chat
+	expiresAt time.Time
+}
+
+// Memory is an in-process LRU cache with ttl in front of a shared cache.
+// Every mutation is published to other replicas, so they evict their copies.
+type Memory struct {
+	next        Cash
+	invalidator Invalidator
+	size        int
+	ttl         time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	// generation grows on every eviction, so a value read from next tier
+	// before an invalidation is not stored after it
+	generation uint64
+}
+
+func New(next Cash, invalidator Invalidator, size int, ttl time.Duration) *Memory {
+	return &Memory{
+		next:        next,
+		invalidator: invalidator,
+		size:        size,
+		ttl:         ttl,
+		order:       list.New(),
+		entries:     make(map[string]*list.Element, size),
+	}
+}
+
+func (m *Memory) SaveChat(ctx context.Context, chat models.Chat) error {
+	const op = "storage.memory.SaveChat"
+
+	err := m.next.SaveChat(ctx, chat)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	m.put(chat)
+
+	return nil
+}
+
+func (m *Memory) GetChat(ctx context.Context, id string) (models.Chat, error) {
+	const op = "storage.memory.GetChat"
+
+	chat, generation, ok := m.get(id)
+	if ok {
+		return chat, nil
+	}
+
+	chat, err := m.next.GetChat(ctx, id)
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	m.putIfCurrent(chat, generation)
+
+	return chat, nil
+}
+
+func (m *Memory) SaveMissingChat(ctx context.Context, id string) error {
+	const op = "storage.memory.SaveMissingChat"
+
+	m.remove(id)
+
+	err := m.next.SaveMissingChat(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (m *Memory) UpdateChat(ctx context.Context, chat models.Chat) error {
+	const op = "storage.memory.UpdateChat"
+
+	err := m.next.UpdateChat(ctx, chat)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	m.invalidate(ctx, chat.ID)
+
+	return nil
+}
+
+func (m *Memory) DeleteChat(ctx context.Context, chatId string) error {
+	const op = "storage.memory.DeleteChat"
+
+	err := m.next.DeleteChat(ctx, chatId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	m.invalidate(ctx, chatId)
+
+	return nil
+}
+
+// Start evicts entries invalidated by other replicas until ctx is done.
+// Messages sent while connection is down are lost, so ttl bounds how long
+// a stale entry may live.
+func (m *Memory) Start(ctx context.Context) {
+	const op = "storage.memory.Start"
+
+	ctx = logger.GetFromCtx(ctx).With(ctx, zap.String("op", op))
+
+	for {
+		err := m.invalidator.ListenInvalidations(ctx, m.remove)
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.GetFromCtx(ctx).Error(ctx, "failed to listen invalidations", zap.Error(err))
+		m.clear()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// invalidate evicts local copy and tells other replicas to do the same.
+func (m *Memory) invalidate(ctx context.Context, chatId string) {
+	m.remove(chatId)
+
+	err := m.invalidator.PublishInvalidation(ctx, chatId)
+	if err != nil {
+		logger.GetFromCtx(ctx).Error(ctx, "failed to publish invalidation", zap.Error(err))
+	}
+}
+
+func (m *Memory) get(id string) (models.Chat, uint64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.entries[id]
+	if !ok {
+		return models.Chat{}, m.generation, false
+	}
+
+	e := elem.Value.(*entry)
+	if time.Now().After(e.expiresAt) {
+		m.order.Remove(elem)
+		delete(m.entries, id)
+		return models.Chat{}, m.generation, false
+	}
+
+	m.order.MoveToFront(elem)
+
+	chat := e.chat
+	chat.ParticipantsId = slices.Clone(e.chat.ParticipantsId)
+
+	return chat, m.generation, true
+}
+
+func (m *Memory) put(chat models.Chat) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.store(chat)
+}
+
+func (m *Memory) putIfCurrent(chat models.Chat, generation uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.generation != generation {
+		return
+	}
+
+	m.store(chat)
+}
+
+func (m *Memory) store(chat models.Chat) {
+	chat.ParticipantsId = slices.Clone(chat.ParticipantsId)
+	e := &entry{
+		chat:      chat,
+		expiresAt: time.Now().Add(m.ttl),
+	}
+
+	if elem, ok := m.entries[chat.ID]; ok {
+		elem.Value = e
+		m.order.MoveToFront(elem)
+		return
+	}
+
+	m.entries[chat.ID] = m.order.PushFront(e)
+
+	for m.order.Len() > m.size {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*entry).chat.ID)
+	}
+}
+
+func (m *Memory) remove(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.generation++
+
+	if elem, ok := m.entries[id]; ok {
+		m.order.Remove(elem)
+		delete(m.entries, id)
+	}
+}
+
+func (m *Memory) clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.generation++
+	m.order.Init()
+	clear(m.entries)
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+)
+
+type fakeCash struct {
+	chats map[string]models.Chat
+	gets  int
+}
+
+func (f *fakeCash) SaveChat(ctx context.Context, chat models.Chat) error {
+	f.chats[chat.ID] = chat
+	return nil
+}
+
+func (f *fakeCash) GetChat(ctx context.Context, id string) (models.Chat, error) {
+	f.gets++
+	return f.chats[id], nil
+}
+
+func (f *fakeCash) SaveMissingChat(ctx context.Context, id string) error {
+	return nil
+}
+
+func (f *fakeCash) UpdateChat(ctx context.Context, chat models.Chat) error {
+	f.chats[chat.ID] = chat
+	return nil
+}
+
+func (f *fakeCash) DeleteChat(ctx context.Context, chatId string) error {
+	delete(f.chats, chatId)
+	return nil
+}
+
+type fakeInvalidator struct {
+	published []string
+}
+
+func (f *fakeInvalidator) PublishInvalidation(ctx context.Context, chatId string) error {
+	f.published = append(f.published, chatId)
+	return nil
+}
+
+func (f *fakeInvalidator) ListenInvalidations(ctx context.Context, fn func(chatId string)) error {
+	<-ctx.Done()
+	return nil
+}
+
+func TestMemory_GetChat(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		size     int
+		ttl      time.Duration
+		prepare  func(m *Memory)
+		id       string
+		wantGets int
+	}{
+		{
+			name:     "served from memory",
+			size:     2,
+			ttl:      time.Minute,
+			prepare:  func(m *Memory) { _, _ = m.GetChat(ctx, "a") },
+			id:       "a",
+			wantGets: 1,
+		},
+		{
+			name: "least recently used is evicted",
+			size: 2,
+			ttl:  time.Minute,
+			prepare: func(m *Memory) {
+				_, _ = m.GetChat(ctx, "a")
+				_, _ = m.GetChat(ctx, "b")
+				_, _ = m.GetChat(ctx, "a")
+				_, _ = m.GetChat(ctx, "c")
+			},
+			id:       "b",
+			wantGets: 4,
+		},
+		{
+			name:     "expired entry is reloaded",
+			size:     2,
+			ttl:      -time.Second,
+			prepare:  func(m *Memory) { _, _ = m.GetChat(ctx, "a") },
+			id:       "a",
+			wantGets: 2,
+		},
+		{
+			name: "remote invalidation evicts entry",
+			size: 2,
+			ttl:  time.Minute,
+			prepare: func(m *Memory) {
+				_, _ = m.GetChat(ctx, "a")
+				m.remove("a")
+			},
+			id:       "a",
+			wantGets: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &fakeCash{chats: map[string]models.Chat{
+				"a": {ID: "a"},
+				"b": {ID: "b"},
+				"c": {ID: "c"},
+			}}
+			m := New(next, &fakeInvalidator{}, tt.size, tt.ttl)
+
+			tt.prepare(m)
+
+			got, err := m.GetChat(ctx, tt.id)
+			if err != nil {
+				t.Fatalf("Memory.GetChat() error = %v", err)
+			}
+			if got.ID != tt.id {
+				t.Errorf("Memory.GetChat() = %v, want id %v", got, tt.id)
+			}
+			if next.gets != tt.wantGets {
+				t.Errorf("next tier reads = %d, want %d", next.gets, tt.wantGets)
+			}
+		})
+	}
+}
+
+func TestMemory_UpdateChat(t *testing.T) {
+	ctx := context.Background()
+
+	next := &fakeCash{chats: map[string]models.Chat{"a": {ID: "a", Name: "old"}}}
+	invalidator := &fakeInvalidator{}
+	m := New(next, invalidator, 2, time.Minute)
+
+	_, _ = m.GetChat(ctx, "a")
+
+	err := m.UpdateChat(ctx, models.Chat{ID: "a", Name: "new"})
+	if err != nil {
+		t.Fatalf("Memory.UpdateChat() error = %v", err)
+	}
+
+	got, _ := m.GetChat(ctx, "a")
+	if got.Name != "new" {
+		t.Errorf("Memory.GetChat() name = %q, want %q", got.Name, "new")
+	}
+	if len(invalidator.published) != 1 || invalidator.published[0] != "a" {
+		t.Errorf("published invalidations = %v, want [a]", invalidator.published)
+	}
+}
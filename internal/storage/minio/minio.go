@@ -3,9 +3,11 @@ package minio
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/AlexMickh/speak-chat/internal/models"
@@ -34,23 +36,61 @@ type Client interface {
 		objectName string,
 		opts minio.RemoveObjectOptions,
 	) error
+	ListObjects(
+		ctx context.Context,
+		bucketName string,
+		opts minio.ListObjectsOptions,
+	) <-chan minio.ObjectInfo
 }
 
+// URL modes supported by Minio.
+//
+// URLModePresigned hands out presigned urls that expire after the configured
+// duration. URLModePublic serves avatars from a stable public (CDN) base url,
+// so urls never expire.
+const (
+	URLModePresigned = "presigned"
+	URLModePublic    = "public"
+)
+
+var ErrUnknownURLMode = errors.New("unknown url mode")
+
 type Minio struct {
-	mc         Client
-	bucketName string
-	expires    time.Duration
+	mc            Client
+	bucketName    string
+	expires       time.Duration
+	urlMode       string
+	publicBaseUrl string
 }
 
-func New(mc Client, bucketName string, expires time.Duration) *Minio {
-	return &Minio{
-		mc:         mc,
-		bucketName: bucketName,
-		expires:    expires,
+func New(
+	mc Client,
+	bucketName string,
+	expires time.Duration,
+	urlMode string,
+	publicBaseUrl string,
+) (*Minio, error) {
+	const op = "storage.minio.New"
+
+	if urlMode != URLModePresigned && urlMode != URLModePublic {
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrUnknownURLMode, urlMode)
 	}
+
+	return &Minio{
+		mc:            mc,
+		bucketName:    bucketName,
+		expires:       expires,
+		urlMode:       urlMode,
+		publicBaseUrl: strings.TrimRight(publicBaseUrl, "/"),
+	}, nil
 }
 
-const defaultImage = "avatar.png"
+const (
+	defaultImage = "avatar.png"
+	// objects are keyed by content hash and never change,
+	// so they can be cached forever
+	immutableCacheControl = "public, max-age=31536000, immutable"
+)
 
 func (m *Minio) SaveAvatar(ctx context.Context, avatar *models.Avatar) (string, time.Time, error) {
 	const op = "storage.minio.SaveAvatar"
@@ -72,7 +112,10 @@ func (m *Minio) SaveAvatar(ctx context.Context, avatar *models.Avatar) (string,
 		avatar.ID,
 		reader,
 		int64(len(avatar.Data)),
-		minio.PutObjectOptions{ContentType: "image/png"},
+		minio.PutObjectOptions{
+			ContentType:  "image/png",
+			CacheControl: immutableCacheControl,
+		},
 	)
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
@@ -89,6 +132,15 @@ func (m *Minio) SaveAvatar(ctx context.Context, avatar *models.Avatar) (string,
 func (m *Minio) GetAvatarUrl(ctx context.Context, avatarId string) (string, time.Time, error) {
 	const op = "storage.minio.GetAvatar"
 
+	if avatarId == "" {
+		avatarId = defaultImage
+	}
+
+	if m.isPublic() {
+		// zero expire time means that url never expires
+		return m.publicBaseUrl + "/" + avatarId, time.Time{}, nil
+	}
+
 	url, err := m.mc.PresignedGetObject(ctx, m.bucketName, avatarId, m.expires, nil)
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
@@ -99,15 +151,15 @@ func (m *Minio) GetAvatarUrl(ctx context.Context, avatarId string) (string, time
 	return url.String(), expires, nil
 }
 
-func (m *Minio) UpdateAvatar(ctx context.Context, avatar models.Avatar) (string, time.Time, error) {
-	const op = "storage.minio.UpdateAvatar"
+func (m *Minio) DeleteAvatar(ctx context.Context, avatarId string) (string, time.Time, error) {
+	const op = "storage.minio.DeleteAvatar"
 
-	_, _, err := m.DeleteAvatar(ctx, avatar.ID)
+	err := m.mc.RemoveObject(ctx, m.bucketName, avatarId, minio.RemoveObjectOptions{})
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	url, expires, err := m.SaveAvatar(ctx, &avatar)
+	url, expires, err := m.GetAvatarUrl(ctx, defaultImage)
 	if err != nil {
 		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -115,18 +167,46 @@ func (m *Minio) UpdateAvatar(ctx context.Context, avatar models.Avatar) (string,
 	return url, expires, nil
 }
 
-func (m *Minio) DeleteAvatar(ctx context.Context, avatarId string) (string, time.Time, error) {
-	const op = "storage.minio.DeleteAvatar"
+// ListAvatars returns all objects stored in the bucket except the default image.
+func (m *Minio) ListAvatars(ctx context.Context) ([]models.AvatarObject, error) {
+	const op = "storage.minio.ListAvatars"
+
+	var objects []models.AvatarObject
+	var err error
+	// channel must be drained entirely, otherwise listing goroutine leaks
+	for info := range m.mc.ListObjects(ctx, m.bucketName, minio.ListObjectsOptions{Recursive: true}) {
+		if info.Err != nil {
+			err = info.Err
+			continue
+		}
+		if info.Key == defaultImage {
+			continue
+		}
 
-	err := m.mc.RemoveObject(ctx, m.bucketName, avatarId, minio.RemoveObjectOptions{})
+		objects = append(objects, models.AvatarObject{
+			Key:          info.Key,
+			Size:         info.Size,
+			LastModified: info.LastModified,
+		})
+	}
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	url, expires, err := m.GetAvatarUrl(ctx, defaultImage)
+	return objects, nil
+}
+
+func (m *Minio) RemoveAvatar(ctx context.Context, key string) error {
+	const op = "storage.minio.RemoveAvatar"
+
+	err := m.mc.RemoveObject(ctx, m.bucketName, key, minio.RemoveObjectOptions{})
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	return url, expires, nil
+	return nil
+}
+
+func (m *Minio) isPublic() bool {
+	return m.urlMode == URLModePublic
 }
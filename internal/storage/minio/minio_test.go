@@ -0,0 +1,42 @@
+package minio
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		urlMode string
+		wantErr error
+	}{
+		{
+			name:    "presigned",
+			urlMode: URLModePresigned,
+		},
+		{
+			name:    "public",
+			urlMode: URLModePublic,
+		},
+		{
+			name:    "typo",
+			urlMode: "pubilc",
+			wantErr: ErrUnknownURLMode,
+		},
+		{
+			name:    "empty",
+			urlMode: "",
+			wantErr: ErrUnknownURLMode,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(nil, "bucket", time.Hour, tt.urlMode, "https://cdn.example.com")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("New() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
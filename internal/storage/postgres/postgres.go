@@ -18,8 +18,11 @@ type Postgres interface {
 	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
+type txKey struct{}
+
 type Storage struct {
 	db Postgres
 }
@@ -30,6 +33,41 @@ func New(db Postgres) *Storage {
 	}
 }
 
+// WithTx runs fn in a transaction. Storage calls made with ctx passed to fn
+// join it. Nested calls create savepoints, so an inner failure can be
+// handled without aborting the outer transaction.
+func (s *Storage) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	const op = "storage.postgres.WithTx"
+
+	tx, err := s.conn(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// rollback after commit is a no-op, so it only fires on failure or panic
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	err = fn(context.WithValue(ctx, txKey{}, tx))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// conn returns transaction bound to ctx or the pool.
+func (s *Storage) conn(ctx context.Context) Postgres {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+
+	return s.db
+}
+
 // TODO: add pgx errors
 
 func (s *Storage) SaveChat(
@@ -39,14 +77,38 @@ func (s *Storage) SaveChat(
 	description string,
 	chatImageUrl string,
 	imageExireTime time.Time,
+	avatarHash string,
 	chatOwnerId string,
+	chatType string,
 ) error {
 	const op = "storage.postgres.SaveChat"
 
-	sql := `INSERT INTO chat.chats
-			(id, name, description, owner_id, chat_image_url, participants_id, image_expire_time)
-			VALUES ($1, $2, $3, $4, $5, ARRAY[$6], $7)`
-	_, err := s.db.Exec(ctx, sql, id, name, description, chatOwnerId, chatImageUrl, chatOwnerId, imageExireTime)
+	// first avatar version and outbox entry are recorded in the same statement
+	sql := `WITH chat AS (
+				INSERT INTO chat.chats
+				(id, name, description, owner_id, chat_image_url, participants_id, image_expire_time, avatar_hash, type)
+				VALUES ($1, $2, $3, $4, $5, ARRAY[$6], $7, $8, $10)
+				RETURNING id, owner_id, avatar_hash
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				SELECT id, $9, jsonb_build_object('user_id', owner_id) FROM chat
+			)
+			INSERT INTO chat.chat_avatar_versions (chat_id, avatar_hash, created_by)
+			SELECT id, avatar_hash, owner_id FROM chat WHERE avatar_hash <> ''`
+	_, err := s.conn(ctx).Exec(
+		ctx,
+		sql,
+		id,
+		name,
+		description,
+		chatOwnerId,
+		chatImageUrl,
+		chatOwnerId,
+		imageExireTime,
+		avatarHash,
+		models.OutboxChatCreated,
+		chatType,
+	)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -64,10 +126,10 @@ func (s *Storage) GetChat(ctx context.Context, id string) (models.Chat, error) {
 	const op = "storage.postgres.GetChat"
 
 	var chat models.Chat
-	sqlStr := `SELECT id, name, description, chat_image_url, owner_id, participants_id, image_expire_time
+	sqlStr := `SELECT id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
 			FROM chat.chats
-			WHERE id = $1`
-	err := s.db.QueryRow(ctx, sqlStr, id).Scan(
+			WHERE id = $1 AND deleted_at IS NULL`
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, id).Scan(
 		&chat.ID,
 		&chat.Name,
 		&chat.Description,
@@ -75,6 +137,11 @@ func (s *Storage) GetChat(ctx context.Context, id string) (models.Chat, error) {
 		&chat.ChatOwnerId,
 		&chat.ParticipantsId,
 		&chat.ImageExpireTime,
+		&chat.AvatarHash,
+		&chat.Version,
+		&chat.Type,
+		&chat.SubscribersCount,
+		&chat.Public,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -91,8 +158,10 @@ func (s *Storage) GetAllUserChats(ctx context.Context, userId string) ([]models.
 
 	sql := `SELECT id, name, chat_image_url, image_expire_time
 			FROM chat.chats
-			WHERE $1 = ANY(participants_id)`
-	rows, err := s.db.Query(ctx, sql, userId)
+			WHERE ($1 = ANY(participants_id) OR id IN (
+				SELECT chat_id FROM chat.channel_subscribers WHERE user_id = $1
+			)) AND deleted_at IS NULL`
+	rows, err := s.conn(ctx).Query(ctx, sql, userId)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -128,9 +197,9 @@ func (s *Storage) UpdateImageUrl(
 	var chat models.Chat
 	sql := `UPDATE chat.chats 
 			SET chat_image_url = $1, image_expire_time = $2
-			WHERE id = $3
-			RETURNING id, name, description, chat_image_url, chat_owner_id, participants_id, image_expire_time`
-	err := s.db.QueryRow(ctx, sql, chatImageUrl, imageExireTime, chatId).Scan(
+			WHERE id = $3 AND deleted_at IS NULL
+			RETURNING id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public`
+	err := s.conn(ctx).QueryRow(ctx, sql, chatImageUrl, imageExireTime, chatId).Scan(
 		&chat.ID,
 		&chat.Name,
 		&chat.Description,
@@ -138,6 +207,11 @@ func (s *Storage) UpdateImageUrl(
 		&chat.ChatOwnerId,
 		&chat.ParticipantsId,
 		&chat.ImageExpireTime,
+		&chat.AvatarHash,
+		&chat.Version,
+		&chat.Type,
+		&chat.SubscribersCount,
+		&chat.Public,
 	)
 	if err != nil {
 		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
@@ -154,21 +228,23 @@ func (s *Storage) UpdateChatInfo(
 	description string,
 	chatImageUrl string,
 	imageExireTime time.Time,
+	avatarHash string,
+	expectedVersion int64,
 ) (models.Chat, error) {
 	const op = "storage.postgres.UpdateChatInfo"
 
 	var sb strings.Builder
 
-	_, err := sb.WriteString("UPDATE chat.chats ")
+	_, err := sb.WriteString("WITH updated AS (UPDATE chat.chats SET version = version + 1")
 	if err != nil {
 		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	counter := 1
-	args := make([]any, 0, 6)
+	args := make([]any, 0, 9)
 
 	if name != "" {
-		_, err = sb.WriteString(fmt.Sprintf("SET name = $%d", counter))
+		_, err = sb.WriteString(fmt.Sprintf(", name = $%d", counter))
 		if err != nil {
 			return models.Chat{}, fmt.Errorf("%s: %w", op, err)
 		}
@@ -177,45 +253,33 @@ func (s *Storage) UpdateChatInfo(
 		args = append(args, name)
 	}
 	if description != "" {
-		if counter == 1 {
-			_, err = sb.WriteString(fmt.Sprintf("SET description = $%d", counter))
-			if err != nil {
-				return models.Chat{}, fmt.Errorf("%s: %w", op, err)
-			}
-		} else {
-			_, err = sb.WriteString(fmt.Sprintf(", description = $%d", counter))
-			if err != nil {
-				return models.Chat{}, fmt.Errorf("%s: %w", op, err)
-			}
+		_, err = sb.WriteString(fmt.Sprintf(", description = $%d", counter))
+		if err != nil {
+			return models.Chat{}, fmt.Errorf("%s: %w", op, err)
 		}
 		counter++
 		args = append(args, description)
 	}
 	if chatImageUrl != "" {
-		if counter == 1 {
-			_, err = sb.WriteString(
-				fmt.Sprintf("SET chat_image_url = $%d, image_expire_time = $%d", counter, counter+1),
-			)
-			if err != nil {
-				return models.Chat{}, fmt.Errorf("%s: %w", op, err)
-			}
-		} else {
-			_, err = sb.WriteString(
-				fmt.Sprintf(", chat_image_url = $%d, image_expire_time = $%d", counter, counter+1),
-			)
-			if err != nil {
-				return models.Chat{}, fmt.Errorf("%s: %w", op, err)
-			}
+		_, err = sb.WriteString(
+			fmt.Sprintf(
+				", chat_image_url = $%d, image_expire_time = $%d, avatar_hash = $%d",
+				counter, counter+1, counter+2,
+			),
+		)
+		if err != nil {
+			return models.Chat{}, fmt.Errorf("%s: %w", op, err)
 		}
-		counter += 2
+		counter += 3
 		args = append(args, chatImageUrl)
 		args = append(args, imageExireTime)
+		args = append(args, avatarHash)
 	}
 
 	_, err = sb.WriteString(
-		fmt.Sprintf(` WHERE id = $%d AND owner_id = $%d 
-					 RETURNING id, name, description, chat_image_url, owner_id, participants_id, image_expire_time`,
-			counter, counter+1),
+		fmt.Sprintf(` WHERE id = $%d AND owner_id = $%d AND deleted_at IS NULL AND ($%d::bigint = 0 OR version = $%d)
+					 RETURNING id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public)`,
+			counter, counter+1, counter+3, counter+3),
 	)
 	if err != nil {
 		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
@@ -224,8 +288,42 @@ func (s *Storage) UpdateChatInfo(
 	args = append(args, chatId)
 	args = append(args, userId)
 
+	// new avatar becomes a new version in the same statement
+	if chatImageUrl != "" {
+		_, err = sb.WriteString(
+			fmt.Sprintf(`, version AS (
+						INSERT INTO chat.chat_avatar_versions (chat_id, avatar_hash, created_by)
+						SELECT id, avatar_hash, $%d FROM updated WHERE avatar_hash <> '')`,
+				counter+1),
+		)
+		if err != nil {
+			return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	_, err = sb.WriteString(
+		fmt.Sprintf(`, outbox AS (
+					INSERT INTO chat.outbox (chat_id, kind, payload)
+					SELECT id, $%d, jsonb_build_object('user_id', $%d::text) FROM updated)`,
+			counter+2, counter+1),
+	)
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	args = append(args, models.OutboxChatUpdated)
+	args = append(args, expectedVersion)
+
+	_, err = sb.WriteString(
+		` SELECT id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+		 FROM updated`,
+	)
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
 	var chat models.Chat
-	err = s.db.QueryRow(ctx, sb.String(), args...).Scan(
+	err = s.conn(ctx).QueryRow(ctx, sb.String(), args...).Scan(
 		&chat.ID,
 		&chat.Name,
 		&chat.Description,
@@ -233,8 +331,18 @@ func (s *Storage) UpdateChatInfo(
 		&chat.ChatOwnerId,
 		&chat.ParticipantsId,
 		&chat.ImageExpireTime,
+		&chat.AvatarHash,
+		&chat.Version,
+		&chat.Type,
+		&chat.SubscribersCount,
+		&chat.Public,
 	)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if conflictErr := s.checkVersion(ctx, chatId, expectedVersion); conflictErr != nil {
+				return models.Chat{}, fmt.Errorf("%s: %w", op, conflictErr)
+			}
+		}
 		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -246,28 +354,1602 @@ func (s *Storage) AddParticipant(
 	userId string,
 	chatId string,
 	participantId string,
-) error {
+) (models.Chat, error) {
 	const op = "storage.postgres.AddParticipant"
 
-	sql := `UPDATE chat.chats
-			SET participants_id = array_append(participants_id, $1)
-			WHERE id = $2 AND owner_id = $3`
-	_, err := s.db.Exec(ctx, sql, participantId, chatId, userId)
+	var chat models.Chat
+	sql := `WITH updated AS (
+				UPDATE chat.chats
+				SET participants_id = array_append(participants_id, $1), version = version + 1
+				WHERE id = $2 AND owner_id = $3 AND deleted_at IS NULL
+				RETURNING id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				SELECT id, $4, jsonb_build_object('user_id', $3::text, 'participant_id', $1::text) FROM updated
+			)
+			SELECT id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			FROM updated`
+	err := s.conn(ctx).QueryRow(ctx, sql, participantId, chatId, userId, models.OutboxParticipantAdded).Scan(
+		&chat.ID,
+		&chat.Name,
+		&chat.Description,
+		&chat.ChatImageUrl,
+		&chat.ChatOwnerId,
+		&chat.ParticipantsId,
+		&chat.ImageExpireTime,
+		&chat.AvatarHash,
+		&chat.Version,
+		&chat.Type,
+		&chat.SubscribersCount,
+		&chat.Public,
+	)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	return chat, nil
+}
+
+// AcquireAvatar adds a reference to avatar with given content hash.
+// It reports whether the avatar is new and its blob has to be uploaded.
+func (s *Storage) AcquireAvatar(ctx context.Context, hash string, size int64) (bool, error) {
+	const op = "storage.postgres.AcquireAvatar"
+
+	// lock makes it wait for gc that is removing the same content,
+	// so a blob removed by gc is uploaded again
+	var created bool
+	sql := `WITH lock AS (
+				SELECT pg_advisory_xact_lock(hashtextextended($1, 0))
+			)
+			INSERT INTO chat.avatars (hash, size, ref_count)
+			SELECT $1, $2, 1 FROM lock
+			ON CONFLICT (hash) DO UPDATE
+			SET ref_count = chat.avatars.ref_count + 1, updated_at = CURRENT_TIMESTAMP
+			RETURNING xmax = 0`
+	err := s.conn(ctx).QueryRow(ctx, sql, hash, size).Scan(&created)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+// IsAvatarInUse reports whether object with given key is referenced by any
+// chat or avatar version, so versions can always be restored.
+func (s *Storage) IsAvatarInUse(ctx context.Context, key string) (bool, error) {
+	const op = "storage.postgres.IsAvatarInUse"
+
+	var inUse bool
+	sql := `SELECT EXISTS(SELECT 1 FROM chat.avatars WHERE hash = $1 AND ref_count > 0)
+			OR EXISTS(SELECT 1 FROM chat.chats WHERE avatar_hash = $1)
+			OR EXISTS(SELECT 1 FROM chat.chat_avatar_versions WHERE avatar_hash = $1)`
+	err := s.conn(ctx).QueryRow(ctx, sql, key).Scan(&inUse)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return inUse, nil
+}
+
+func (s *Storage) GetAvatarVersions(ctx context.Context, chatId string) ([]models.AvatarVersion, error) {
+	const op = "storage.postgres.GetAvatarVersions"
+
+	sql := `SELECT id, chat_id, avatar_hash, COALESCE(created_by, ''), created_at
+			FROM chat.chat_avatar_versions
+			WHERE chat_id = $1
+			ORDER BY created_at DESC, id DESC`
+	rows, err := s.conn(ctx).Query(ctx, sql, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var versions []models.AvatarVersion
+	for rows.Next() {
+		var version models.AvatarVersion
+
+		err = rows.Scan(
+			&version.ID,
+			&version.ChatId,
+			&version.AvatarHash,
+			&version.CreatedBy,
+			&version.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		versions = append(versions, version)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return versions, nil
+}
+
+func (s *Storage) GetAvatarVersion(ctx context.Context, chatId string, versionId int64) (models.AvatarVersion, error) {
+	const op = "storage.postgres.GetAvatarVersion"
+
+	var version models.AvatarVersion
+	sqlStr := `SELECT id, chat_id, avatar_hash, COALESCE(created_by, ''), created_at
+			FROM chat.chat_avatar_versions
+			WHERE id = $1 AND chat_id = $2`
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, versionId, chatId).Scan(
+		&version.ID,
+		&version.ChatId,
+		&version.AvatarHash,
+		&version.CreatedBy,
+		&version.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AvatarVersion{}, fmt.Errorf("%s: %w", op, storage.ErrAvatarVersionNotFound)
+		}
+		return models.AvatarVersion{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+// DeleteAvatar removes avatar row if it has no references left and reports
+// whether its blob can be removed. False is returned when the row was
+// referenced again, e.g. by a concurrent upload of the same content.
+// Objects that never had a row are reported removable.
+// It must run in transaction that also removes the blob: AcquireAvatar of
+// the same content waits for it, so it never sees the row gone while
+// the blob is still about to be removed.
+func (s *Storage) DeleteAvatar(ctx context.Context, hash string) (bool, error) {
+	const op = "storage.postgres.DeleteAvatar"
+
+	// lock goes in its own statement, so the delete sees rows committed
+	// by uploads that held it
+	_, err := s.conn(ctx).Exec(ctx, "SELECT pg_advisory_xact_lock(hashtextextended($1, 0))", hash)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var deleted bool
+	sql := `WITH deleted AS (
+				DELETE FROM chat.avatars WHERE hash = $1 AND ref_count = 0
+				RETURNING hash
+			)
+			SELECT EXISTS(SELECT 1 FROM deleted)
+				OR NOT EXISTS(SELECT 1 FROM chat.avatars WHERE hash = $1)`
+	err = s.conn(ctx).QueryRow(ctx, sql, hash).Scan(&deleted)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deleted, nil
 }
 
-func (s *Storage) DeleteChat(ctx context.Context, userId, chatId string, ch chan error) {
+// DeleteChat hides chat until it is restored or purged.
+// Avatar references are kept, so restored chat gets its avatars back.
+func (s *Storage) DeleteChat(
+	ctx context.Context,
+	userId string,
+	chatId string,
+	expectedVersion int64,
+	ch chan error,
+) {
 	const op = "storage.postgres.DeleteChat"
 
-	sql := "DELETE FROM chat.chats WHERE id = $1 AND owner_id = $2"
-	_, err := s.db.Exec(ctx, sql, chatId, userId)
+	sqlStr := `WITH deleted AS (
+				UPDATE chat.chats
+				SET deleted_at = CURRENT_TIMESTAMP, version = version + 1
+				WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL AND ($4::bigint = 0 OR version = $4)
+				RETURNING id
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				SELECT id, $3, jsonb_build_object('user_id', $2::text) FROM deleted
+			)
+			SELECT count(*) FROM deleted`
+	var deleted int
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, chatId, userId, models.OutboxChatDeleted, expectedVersion).Scan(&deleted)
 	if err != nil {
 		ch <- fmt.Errorf("%s: %w", op, err)
+		return
+	}
+	if deleted == 0 {
+		if err = s.checkVersion(ctx, chatId, expectedVersion); err != nil {
+			ch <- fmt.Errorf("%s: %w", op, err)
+			return
+		}
 	}
 
 	ch <- nil
 }
+
+// RestoreChat brings back chat deleted less than window ago.
+func (s *Storage) RestoreChat(
+	ctx context.Context,
+	userId string,
+	chatId string,
+	window time.Duration,
+) (models.Chat, error) {
+	const op = "storage.postgres.RestoreChat"
+
+	var chat models.Chat
+	sqlStr := `WITH restored AS (
+				UPDATE chat.chats
+				SET deleted_at = NULL, version = version + 1
+				WHERE id = $1 AND owner_id = $2 AND deleted_at IS NOT NULL
+				AND deleted_at > CURRENT_TIMESTAMP - $3 * INTERVAL '1 millisecond'
+				RETURNING id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				SELECT id, $4, jsonb_build_object('user_id', $2::text) FROM restored
+			)
+			SELECT id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			FROM restored`
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, chatId, userId, window.Milliseconds(), models.OutboxChatRestored).Scan(
+		&chat.ID,
+		&chat.Name,
+		&chat.Description,
+		&chat.ChatImageUrl,
+		&chat.ChatOwnerId,
+		&chat.ParticipantsId,
+		&chat.ImageExpireTime,
+		&chat.AvatarHash,
+		&chat.Version,
+		&chat.Type,
+		&chat.SubscribersCount,
+		&chat.Public,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Chat{}, fmt.Errorf("%s: %w", op, storage.ErrChatNotFound)
+		}
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chat, nil
+}
+
+// PurgeDeletedChats removes up to limit chats deleted more than window ago
+// and returns how many were removed. Their avatar references are released,
+// so unused blobs are collected by gc.
+func (s *Storage) PurgeDeletedChats(ctx context.Context, window time.Duration, limit int) (int, error) {
+	const op = "storage.postgres.PurgeDeletedChats"
+
+	sqlStr := `WITH purged AS (
+				DELETE FROM chat.chats
+				WHERE id IN (
+					SELECT id FROM chat.chats
+					WHERE deleted_at <= CURRENT_TIMESTAMP - $1 * INTERVAL '1 millisecond'
+					ORDER BY deleted_at
+					LIMIT $2
+					FOR UPDATE SKIP LOCKED
+				)
+				RETURNING id
+			), webhooks AS (
+				DELETE FROM chat.webhooks WHERE chat_id IN (SELECT id FROM purged)
+			), invites AS (
+				DELETE FROM chat.invites WHERE chat_id IN (SELECT id FROM purged)
+			), join_requests AS (
+				DELETE FROM chat.chat_join_requests WHERE chat_id IN (SELECT id FROM purged)
+			), subscribers AS (
+				DELETE FROM chat.channel_subscribers WHERE chat_id IN (SELECT id FROM purged)
+			), bans AS (
+				DELETE FROM chat.bans WHERE chat_id IN (SELECT id FROM purged)
+			), mutes AS (
+				DELETE FROM chat.mutes WHERE chat_id IN (SELECT id FROM purged)
+			), versions AS (
+				DELETE FROM chat.chat_avatar_versions
+				WHERE chat_id IN (SELECT id FROM purged)
+				RETURNING avatar_hash
+			), refs AS (
+				UPDATE chat.avatars a
+				SET ref_count = GREATEST(a.ref_count - v.refs, 0), updated_at = CURRENT_TIMESTAMP
+				FROM (SELECT avatar_hash, count(*) AS refs FROM versions GROUP BY avatar_hash) v
+				WHERE a.hash = v.avatar_hash
+			)
+			SELECT count(*) FROM purged`
+	var purged int
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, window.Milliseconds(), limit).Scan(&purged)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return purged, nil
+}
+
+// checkVersion returns storage.ErrVersionConflict when chat exists
+// but its version differs from expected one. Zero version matches any.
+func (s *Storage) checkVersion(ctx context.Context, chatId string, expectedVersion int64) error {
+	const op = "storage.postgres.checkVersion"
+
+	if expectedVersion == 0 {
+		return nil
+	}
+
+	var version int64
+	sqlStr := "SELECT version FROM chat.chats WHERE id = $1 AND deleted_at IS NULL"
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, chatId).Scan(&version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if version != expectedVersion {
+		return fmt.Errorf("%s: %w", op, storage.ErrVersionConflict)
+	}
+
+	return nil
+}
+
+// ClaimOutbox leases a batch of due outbox entries. Leased entries are hidden
+// from other relays until lease ends, so a crashed relay only delays them.
+func (s *Storage) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEntry, error) {
+	const op = "storage.postgres.ClaimOutbox"
+
+	sql := `UPDATE chat.outbox
+			SET available_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 millisecond'
+			WHERE id IN (
+				SELECT id FROM chat.outbox
+				WHERE available_at <= CURRENT_TIMESTAMP
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, chat_id, kind, payload, attempts, created_at`
+	rows, err := s.conn(ctx).Query(ctx, sql, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var entries []models.OutboxEntry
+	for rows.Next() {
+		var entry models.OutboxEntry
+
+		err = rows.Scan(&entry.ID, &entry.ChatId, &entry.Kind, &entry.Payload, &entry.Attempts, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+func (s *Storage) CompleteOutbox(ctx context.Context, id int64) error {
+	const op = "storage.postgres.CompleteOutbox"
+
+	sql := "DELETE FROM chat.outbox WHERE id = $1"
+	_, err := s.conn(ctx).Exec(ctx, sql, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) FailOutbox(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	const op = "storage.postgres.FailOutbox"
+
+	sql := `UPDATE chat.outbox
+			SET attempts = attempts + 1, last_error = $2, available_at = $3
+			WHERE id = $1`
+	_, err := s.conn(ctx).Exec(ctx, sql, id, reason, retryAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// LockChat returns chat and locks it until the end of transaction,
+// so the state seen by caller is the one its update is applied to.
+func (s *Storage) LockChat(ctx context.Context, id string) (models.Chat, error) {
+	const op = "storage.postgres.LockChat"
+
+	var chat models.Chat
+	sqlStr := `SELECT id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			FROM chat.chats
+			WHERE id = $1 AND deleted_at IS NULL
+			FOR UPDATE`
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, id).Scan(
+		&chat.ID,
+		&chat.Name,
+		&chat.Description,
+		&chat.ChatImageUrl,
+		&chat.ChatOwnerId,
+		&chat.ParticipantsId,
+		&chat.ImageExpireTime,
+		&chat.AvatarHash,
+		&chat.Version,
+		&chat.Type,
+		&chat.SubscribersCount,
+		&chat.Public,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Chat{}, fmt.Errorf("%s: %w", op, storage.ErrChatNotFound)
+		}
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chat, nil
+}
+
+func (s *Storage) SaveAuditEntry(ctx context.Context, entry models.AuditEntry) error {
+	const op = "storage.postgres.SaveAuditEntry"
+
+	sql := `INSERT INTO chat.audit_log (chat_id, actor_id, action, diff, request_id)
+			VALUES ($1, $2, $3, $4, $5)`
+	_, err := s.conn(ctx).Exec(
+		ctx,
+		sql,
+		entry.ChatId,
+		entry.ActorId,
+		entry.Action,
+		entry.Diff,
+		entry.RequestId,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetAuditEntries returns up to limit entries of the chat older than
+// beforeId, newest first. Zero beforeId starts from the newest entry.
+func (s *Storage) GetAuditEntries(
+	ctx context.Context,
+	chatId string,
+	beforeId int64,
+	limit int,
+) ([]models.AuditEntry, error) {
+	const op = "storage.postgres.GetAuditEntries"
+
+	sql := `SELECT id, chat_id, actor_id, action, diff, request_id, created_at
+			FROM chat.audit_log
+			WHERE chat_id = $1 AND ($2::bigint = 0 OR id < $2)
+			ORDER BY id DESC
+			LIMIT $3`
+	rows, err := s.conn(ctx).Query(ctx, sql, chatId, beforeId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+
+		err = rows.Scan(
+			&entry.ID,
+			&entry.ChatId,
+			&entry.ActorId,
+			&entry.Action,
+			&entry.Diff,
+			&entry.RequestId,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+// CreateAuditPartition creates partition of audit log for given month.
+// Rows of the month that already landed in the default partition, because
+// worker was late or down, are moved to the new partition.
+func (s *Storage) CreateAuditPartition(ctx context.Context, month time.Time) error {
+	const op = "storage.postgres.CreateAuditPartition"
+
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	name := "chat.audit_log_" + from.Format("2006_01")
+
+	err := s.WithTx(ctx, func(ctx context.Context) error {
+		var exists bool
+		err := s.conn(ctx).QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", name).Scan(&exists)
+		if err != nil || exists {
+			return err
+		}
+
+		// default partition can not get a partition for rows it holds,
+		// so it is detached until they are moved
+		var misplaced bool
+		err = s.conn(ctx).QueryRow(
+			ctx,
+			"SELECT EXISTS(SELECT 1 FROM chat.audit_log_default WHERE created_at >= $1 AND created_at < $2)",
+			from,
+			to,
+		).Scan(&misplaced)
+		if err != nil {
+			return err
+		}
+
+		// partition bounds can not be passed as parameters
+		create := fmt.Sprintf(
+			`CREATE TABLE %s
+			PARTITION OF chat.audit_log
+			FOR VALUES FROM ('%s') TO ('%s')`,
+			name,
+			from.Format(time.DateOnly),
+			to.Format(time.DateOnly),
+		)
+		if !misplaced {
+			_, err = s.conn(ctx).Exec(ctx, create)
+			return err
+		}
+
+		_, err = s.conn(ctx).Exec(ctx, "ALTER TABLE chat.audit_log DETACH PARTITION chat.audit_log_default")
+		if err != nil {
+			return err
+		}
+
+		_, err = s.conn(ctx).Exec(ctx, create)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.conn(ctx).Exec(
+			ctx,
+			`WITH moved AS (
+				DELETE FROM chat.audit_log_default
+				WHERE created_at >= $1 AND created_at < $2
+				RETURNING id, chat_id, actor_id, action, diff, request_id, created_at
+			)
+			INSERT INTO chat.audit_log (id, chat_id, actor_id, action, diff, request_id, created_at)
+			SELECT id, chat_id, actor_id, action, diff, request_id, created_at FROM moved`,
+			from,
+			to,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.conn(ctx).Exec(ctx, "ALTER TABLE chat.audit_log ATTACH PARTITION chat.audit_log_default DEFAULT")
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	const op = "storage.postgres.SaveWebhook"
+
+	sql := `INSERT INTO chat.webhooks (id, chat_id, url, secret, created_by)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING created_at`
+	err := s.conn(ctx).QueryRow(
+		ctx,
+		sql,
+		webhook.ID,
+		webhook.ChatId,
+		webhook.Url,
+		webhook.Secret,
+		webhook.CreatedBy,
+	).Scan(&webhook.CreatedAt)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+func (s *Storage) GetWebhooks(ctx context.Context, chatId string) ([]models.Webhook, error) {
+	const op = "storage.postgres.GetWebhooks"
+
+	sql := `SELECT id, chat_id, url, secret, created_by, created_at
+			FROM chat.webhooks
+			WHERE chat_id = $1
+			ORDER BY created_at`
+	rows, err := s.conn(ctx).Query(ctx, sql, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		var webhook models.Webhook
+
+		err = rows.Scan(
+			&webhook.ID,
+			&webhook.ChatId,
+			&webhook.Url,
+			&webhook.Secret,
+			&webhook.CreatedBy,
+			&webhook.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, chatId, webhookId string) (models.Webhook, error) {
+	const op = "storage.postgres.GetWebhook"
+
+	var webhook models.Webhook
+	sqlStr := `SELECT id, chat_id, url, secret, created_by, created_at
+			FROM chat.webhooks
+			WHERE id = $1 AND chat_id = $2`
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, webhookId, chatId).Scan(
+		&webhook.ID,
+		&webhook.ChatId,
+		&webhook.Url,
+		&webhook.Secret,
+		&webhook.CreatedBy,
+		&webhook.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Webhook{}, fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+		}
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+// DeleteWebhook removes webhook with its pending deliveries.
+func (s *Storage) DeleteWebhook(ctx context.Context, chatId, webhookId string) error {
+	const op = "storage.postgres.DeleteWebhook"
+
+	sql := "DELETE FROM chat.webhooks WHERE id = $1 AND chat_id = $2"
+	tag, err := s.conn(ctx).Exec(ctx, sql, webhookId, chatId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+	}
+
+	return nil
+}
+
+// EnqueueWebhookDeliveries schedules event delivery to every webhook
+// of the chat. Enqueueing the same event twice is a no-op.
+func (s *Storage) EnqueueWebhookDeliveries(
+	ctx context.Context,
+	chatId string,
+	eventId string,
+	eventType string,
+	payload []byte,
+) error {
+	const op = "storage.postgres.EnqueueWebhookDeliveries"
+
+	sql := `INSERT INTO chat.webhook_deliveries (webhook_id, event_id, event_type, payload)
+			SELECT id, $2, $3, $4 FROM chat.webhooks WHERE chat_id = $1
+			ON CONFLICT (webhook_id, event_id) DO NOTHING`
+	_, err := s.conn(ctx).Exec(ctx, sql, chatId, eventId, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ClaimWebhookDeliveries leases a batch of due deliveries,
+// same as ClaimOutbox does for outbox entries.
+func (s *Storage) ClaimWebhookDeliveries(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]models.WebhookDelivery, error) {
+	const op = "storage.postgres.ClaimWebhookDeliveries"
+
+	sql := `WITH claimed AS (
+				UPDATE chat.webhook_deliveries
+				SET available_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 millisecond'
+				WHERE id IN (
+					SELECT id FROM chat.webhook_deliveries
+					WHERE available_at <= CURRENT_TIMESTAMP
+					ORDER BY id
+					LIMIT $1
+					FOR UPDATE SKIP LOCKED
+				)
+				RETURNING id, webhook_id, event_id, event_type, payload, attempts
+			)
+			SELECT c.id, c.webhook_id, w.url, w.secret, c.event_id, c.event_type, c.payload, c.attempts
+			FROM claimed c
+			JOIN chat.webhooks w ON w.id = c.webhook_id
+			ORDER BY c.id`
+	rows, err := s.conn(ctx).Query(ctx, sql, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+
+		err = rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookId,
+			&delivery.Url,
+			&delivery.Secret,
+			&delivery.EventId,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Attempts,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+func (s *Storage) CompleteWebhookDelivery(ctx context.Context, id int64) error {
+	const op = "storage.postgres.CompleteWebhookDelivery"
+
+	sql := "DELETE FROM chat.webhook_deliveries WHERE id = $1"
+	_, err := s.conn(ctx).Exec(ctx, sql, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) FailWebhookDelivery(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	const op = "storage.postgres.FailWebhookDelivery"
+
+	sql := `UPDATE chat.webhook_deliveries
+			SET attempts = attempts + 1, last_error = $2, available_at = $3
+			WHERE id = $1`
+	_, err := s.conn(ctx).Exec(ctx, sql, id, reason, retryAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeadLetterWebhookDelivery moves delivery that ran out of attempts
+// to dead letters.
+func (s *Storage) DeadLetterWebhookDelivery(ctx context.Context, id int64, reason string) error {
+	const op = "storage.postgres.DeadLetterWebhookDelivery"
+
+	sql := `WITH moved AS (
+				DELETE FROM chat.webhook_deliveries WHERE id = $1
+				RETURNING webhook_id, event_id, event_type, payload, attempts
+			)
+			INSERT INTO chat.webhook_dead_letters (webhook_id, event_id, event_type, payload, attempts, last_error)
+			SELECT webhook_id, event_id, event_type, payload, attempts + 1, $2 FROM moved`
+	_, err := s.conn(ctx).Exec(ctx, sql, id, reason)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// JoinChat adds participant to chat on behalf of actor without owner check,
+// callers must authorize it. Joining twice is not an error, current chat
+// is returned.
+func (s *Storage) JoinChat(ctx context.Context, chatId, participantId, actorId string) (models.Chat, error) {
+	const op = "storage.postgres.JoinChat"
+
+	var chat models.Chat
+	sqlStr := `WITH updated AS (
+				UPDATE chat.chats
+				SET participants_id = array_append(participants_id, $2), version = version + 1
+				WHERE id = $1 AND deleted_at IS NULL AND NOT ($2 = ANY(participants_id))
+				RETURNING id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				SELECT id, $4, jsonb_build_object('user_id', $3::text, 'participant_id', $2::text) FROM updated
+			)
+			SELECT id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			FROM updated`
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, chatId, participantId, actorId, models.OutboxParticipantAdded).Scan(
+		&chat.ID,
+		&chat.Name,
+		&chat.Description,
+		&chat.ChatImageUrl,
+		&chat.ChatOwnerId,
+		&chat.ParticipantsId,
+		&chat.ImageExpireTime,
+		&chat.AvatarHash,
+		&chat.Version,
+		&chat.Type,
+		&chat.SubscribersCount,
+		&chat.Public,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			chat, err = s.GetChat(ctx, chatId)
+			if err != nil {
+				return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+			}
+			return chat, nil
+		}
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chat, nil
+}
+
+func (s *Storage) SaveInvite(ctx context.Context, invite models.Invite) (models.Invite, error) {
+	const op = "storage.postgres.SaveInvite"
+
+	var expiresAt *time.Time
+	if !invite.ExpiresAt.IsZero() {
+		expiresAt = &invite.ExpiresAt
+	}
+
+	sql := `INSERT INTO chat.invites (code, chat_id, created_by, expires_at, max_uses)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING created_at`
+	err := s.conn(ctx).QueryRow(
+		ctx,
+		sql,
+		invite.Code,
+		invite.ChatId,
+		invite.CreatedBy,
+		expiresAt,
+		invite.MaxUses,
+	).Scan(&invite.CreatedAt)
+	if err != nil {
+		return models.Invite{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return invite, nil
+}
+
+// GetInvites returns invites of the chat that can still be used.
+func (s *Storage) GetInvites(ctx context.Context, chatId string) ([]models.Invite, error) {
+	const op = "storage.postgres.GetInvites"
+
+	sql := `SELECT code, chat_id, created_by, expires_at, max_uses, uses, created_at
+			FROM chat.invites
+			WHERE chat_id = $1 AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+			AND (max_uses = 0 OR uses < max_uses)
+			ORDER BY created_at`
+	rows, err := s.conn(ctx).Query(ctx, sql, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var invites []models.Invite
+	for rows.Next() {
+		var invite models.Invite
+		var expiresAt *time.Time
+
+		err = rows.Scan(
+			&invite.Code,
+			&invite.ChatId,
+			&invite.CreatedBy,
+			&expiresAt,
+			&invite.MaxUses,
+			&invite.Uses,
+			&invite.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if expiresAt != nil {
+			invite.ExpiresAt = *expiresAt
+		}
+
+		invites = append(invites, invite)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return invites, nil
+}
+
+func (s *Storage) RevokeInvite(ctx context.Context, chatId, code string) error {
+	const op = "storage.postgres.RevokeInvite"
+
+	sql := `UPDATE chat.invites
+			SET revoked_at = CURRENT_TIMESTAMP
+			WHERE code = $1 AND chat_id = $2 AND revoked_at IS NULL`
+	tag, err := s.conn(ctx).Exec(ctx, sql, code, chatId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrInviteNotFound)
+	}
+
+	return nil
+}
+
+// ConsumeInvite uses invite once and returns its chat id. Concurrent uses
+// are serialized by row lock, so max uses is never exceeded.
+func (s *Storage) ConsumeInvite(ctx context.Context, code string) (string, error) {
+	const op = "storage.postgres.ConsumeInvite"
+
+	var chatId string
+	sqlStr := `UPDATE chat.invites
+			SET uses = uses + 1
+			WHERE code = $1 AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+			AND (max_uses = 0 OR uses < max_uses)
+			RETURNING chat_id`
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, code).Scan(&chatId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrInviteNotFound)
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chatId, nil
+}
+
+// SaveJoinRequest saves pending join request and notifies chat owner
+// through outbox.
+func (s *Storage) SaveJoinRequest(ctx context.Context, request models.JoinRequest) (models.JoinRequest, error) {
+	const op = "storage.postgres.SaveJoinRequest"
+
+	sql := `WITH request AS (
+				INSERT INTO chat.chat_join_requests (id, chat_id, user_id, message)
+				VALUES ($1, $2, $3, $4)
+				RETURNING id, chat_id, user_id, status, created_at
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				SELECT chat_id, $5, jsonb_build_object('user_id', user_id, 'request_id', id) FROM request
+			)
+			SELECT status, created_at FROM request`
+	err := s.conn(ctx).QueryRow(
+		ctx,
+		sql,
+		request.ID,
+		request.ChatId,
+		request.UserId,
+		request.Message,
+		models.OutboxJoinRequested,
+	).Scan(&request.Status, &request.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23505" {
+				return models.JoinRequest{}, fmt.Errorf("%s: %w", op, storage.ErrJoinRequestExists)
+			}
+		}
+		return models.JoinRequest{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return request, nil
+}
+
+func (s *Storage) GetPendingJoinRequests(ctx context.Context, chatId string) ([]models.JoinRequest, error) {
+	const op = "storage.postgres.GetPendingJoinRequests"
+
+	sql := `SELECT id, chat_id, user_id, message, status, created_at
+			FROM chat.chat_join_requests
+			WHERE chat_id = $1 AND status = $2
+			ORDER BY created_at`
+	rows, err := s.conn(ctx).Query(ctx, sql, chatId, models.JoinRequestPending)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var requests []models.JoinRequest
+	for rows.Next() {
+		var request models.JoinRequest
+		err = rows.Scan(
+			&request.ID,
+			&request.ChatId,
+			&request.UserId,
+			&request.Message,
+			&request.Status,
+			&request.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		requests = append(requests, request)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return requests, nil
+}
+
+// DecideJoinRequest moves pending request to approved or rejected status
+// and notifies requester through outbox.
+func (s *Storage) DecideJoinRequest(
+	ctx context.Context,
+	chatId string,
+	requestId string,
+	status string,
+	deciderId string,
+) (models.JoinRequest, error) {
+	const op = "storage.postgres.DecideJoinRequest"
+
+	kind := models.OutboxJoinRejected
+	if status == models.JoinRequestApproved {
+		kind = models.OutboxJoinApproved
+	}
+
+	var request models.JoinRequest
+	sqlStr := `WITH decided AS (
+				UPDATE chat.chat_join_requests
+				SET status = $3, decided_by = $4, decided_at = CURRENT_TIMESTAMP
+				WHERE id = $1 AND chat_id = $2 AND status = $5
+				RETURNING id, chat_id, user_id, message, status, decided_by, decided_at, created_at
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				SELECT chat_id, $6, jsonb_build_object('user_id', decided_by, 'request_id', id, 'requester_id', user_id)
+				FROM decided
+			)
+			SELECT id, chat_id, user_id, message, status, decided_by, decided_at, created_at FROM decided`
+	err := s.conn(ctx).QueryRow(
+		ctx,
+		sqlStr,
+		requestId,
+		chatId,
+		status,
+		deciderId,
+		models.JoinRequestPending,
+		kind,
+	).Scan(
+		&request.ID,
+		&request.ChatId,
+		&request.UserId,
+		&request.Message,
+		&request.Status,
+		&request.DecidedBy,
+		&request.DecidedAt,
+		&request.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.JoinRequest{}, fmt.Errorf("%s: %w", op, storage.ErrJoinRequestNotFound)
+		}
+		return models.JoinRequest{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return request, nil
+}
+
+// BanUser saves ban and removes banned user from chat participants
+// or channel subscribers.
+// Banning again replaces reason and actor of the ban.
+func (s *Storage) BanUser(ctx context.Context, ban models.Ban) (models.Chat, error) {
+	const op = "storage.postgres.BanUser"
+
+	var chat models.Chat
+	sqlStr := `WITH ban AS (
+				INSERT INTO chat.bans (chat_id, user_id, reason, banned_by)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (chat_id, user_id) DO UPDATE
+				SET reason = EXCLUDED.reason, banned_by = EXCLUDED.banned_by
+			), unsubscribed AS (
+				DELETE FROM chat.channel_subscribers
+				WHERE chat_id = $1 AND user_id = $2
+				RETURNING chat_id
+			), updated AS (
+				UPDATE chat.chats
+				SET participants_id = array_remove(participants_id, $2),
+					subscribers_count = subscribers_count - (SELECT count(*) FROM unsubscribed),
+					version = version + 1
+				WHERE id = $1 AND deleted_at IS NULL
+				AND ($2 = ANY(participants_id) OR EXISTS (SELECT 1 FROM unsubscribed))
+				RETURNING id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				VALUES ($1, $5, jsonb_build_object('user_id', $4::text, 'banned_id', $2::text, 'reason', $3::text))
+			)
+			SELECT id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			FROM updated`
+	err := s.conn(ctx).QueryRow(
+		ctx,
+		sqlStr,
+		ban.ChatId,
+		ban.UserId,
+		ban.Reason,
+		ban.BannedBy,
+		models.OutboxUserBanned,
+	).Scan(
+		&chat.ID,
+		&chat.Name,
+		&chat.Description,
+		&chat.ChatImageUrl,
+		&chat.ChatOwnerId,
+		&chat.ParticipantsId,
+		&chat.ImageExpireTime,
+		&chat.AvatarHash,
+		&chat.Version,
+		&chat.Type,
+		&chat.SubscribersCount,
+		&chat.Public,
+	)
+	if err != nil {
+		// banned user was not a participant, chat is unchanged
+		if errors.Is(err, sql.ErrNoRows) {
+			chat, err = s.GetChat(ctx, ban.ChatId)
+			if err != nil {
+				return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+			}
+			return chat, nil
+		}
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chat, nil
+}
+
+func (s *Storage) UnbanUser(ctx context.Context, chatId, userId, actorId string) error {
+	const op = "storage.postgres.UnbanUser"
+
+	var count int
+	sql := `WITH deleted AS (
+				DELETE FROM chat.bans
+				WHERE chat_id = $1 AND user_id = $2
+				RETURNING chat_id
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				SELECT chat_id, $4, jsonb_build_object('user_id', $3::text, 'unbanned_id', $2::text) FROM deleted
+			)
+			SELECT count(*) FROM deleted`
+	err := s.conn(ctx).QueryRow(ctx, sql, chatId, userId, actorId, models.OutboxUserUnbanned).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrBanNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) IsBanned(ctx context.Context, chatId, userId string) (bool, error) {
+	const op = "storage.postgres.IsBanned"
+
+	var banned bool
+	sql := `SELECT EXISTS(SELECT 1 FROM chat.bans WHERE chat_id = $1 AND user_id = $2)`
+	err := s.conn(ctx).QueryRow(ctx, sql, chatId, userId).Scan(&banned)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return banned, nil
+}
+
+func (s *Storage) GetBans(ctx context.Context, chatId string) ([]models.Ban, error) {
+	const op = "storage.postgres.GetBans"
+
+	sql := `SELECT chat_id, user_id, reason, banned_by, created_at
+			FROM chat.bans
+			WHERE chat_id = $1
+			ORDER BY created_at`
+	rows, err := s.conn(ctx).Query(ctx, sql, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var bans []models.Ban
+	for rows.Next() {
+		var ban models.Ban
+		err = rows.Scan(&ban.ChatId, &ban.UserId, &ban.Reason, &ban.BannedBy, &ban.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		bans = append(bans, ban)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bans, nil
+}
+
+// MuteUser saves mute, muting again replaces previous mute.
+func (s *Storage) MuteUser(ctx context.Context, mute models.Mute) (models.Mute, error) {
+	const op = "storage.postgres.MuteUser"
+
+	sql := `WITH mute AS (
+				INSERT INTO chat.mutes (chat_id, user_id, reason, muted_by, until)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (chat_id, user_id) DO UPDATE
+				SET reason = EXCLUDED.reason, muted_by = EXCLUDED.muted_by,
+					until = EXCLUDED.until, created_at = CURRENT_TIMESTAMP
+				RETURNING created_at
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				VALUES ($1, $6, jsonb_build_object('user_id', $4::text, 'muted_id', $2::text, 'until', $5::timestamptz))
+			)
+			SELECT created_at FROM mute`
+	err := s.conn(ctx).QueryRow(
+		ctx,
+		sql,
+		mute.ChatId,
+		mute.UserId,
+		mute.Reason,
+		mute.MutedBy,
+		mute.Until,
+		models.OutboxUserMuted,
+	).Scan(&mute.CreatedAt)
+	if err != nil {
+		return models.Mute{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return mute, nil
+}
+
+func (s *Storage) UnmuteUser(ctx context.Context, chatId, userId, actorId string) error {
+	const op = "storage.postgres.UnmuteUser"
+
+	var count int
+	sql := `WITH deleted AS (
+				DELETE FROM chat.mutes
+				WHERE chat_id = $1 AND user_id = $2 AND until > CURRENT_TIMESTAMP
+				RETURNING chat_id
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				SELECT chat_id, $4, jsonb_build_object('user_id', $3::text, 'unmuted_id', $2::text) FROM deleted
+			)
+			SELECT count(*) FROM deleted`
+	err := s.conn(ctx).QueryRow(ctx, sql, chatId, userId, actorId, models.OutboxUserUnmuted).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrMuteNotFound)
+	}
+
+	return nil
+}
+
+// GetMute returns active mute of user in chat.
+func (s *Storage) GetMute(ctx context.Context, chatId, userId string) (models.Mute, error) {
+	const op = "storage.postgres.GetMute"
+
+	var mute models.Mute
+	sqlStr := `SELECT chat_id, user_id, reason, muted_by, until, created_at
+			FROM chat.mutes
+			WHERE chat_id = $1 AND user_id = $2 AND until > CURRENT_TIMESTAMP`
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, chatId, userId).Scan(
+		&mute.ChatId,
+		&mute.UserId,
+		&mute.Reason,
+		&mute.MutedBy,
+		&mute.Until,
+		&mute.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Mute{}, fmt.Errorf("%s: %w", op, storage.ErrMuteNotFound)
+		}
+		return models.Mute{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return mute, nil
+}
+
+// GetMutes returns active mutes of the chat.
+func (s *Storage) GetMutes(ctx context.Context, chatId string) ([]models.Mute, error) {
+	const op = "storage.postgres.GetMutes"
+
+	sql := `SELECT chat_id, user_id, reason, muted_by, until, created_at
+			FROM chat.mutes
+			WHERE chat_id = $1 AND until > CURRENT_TIMESTAMP
+			ORDER BY until`
+	rows, err := s.conn(ctx).Query(ctx, sql, chatId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var mutes []models.Mute
+	for rows.Next() {
+		var mute models.Mute
+		err = rows.Scan(&mute.ChatId, &mute.UserId, &mute.Reason, &mute.MutedBy, &mute.Until, &mute.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		mutes = append(mutes, mute)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return mutes, nil
+}
+
+// GetOrCreateDirectChat returns direct chat of two users, creating it
+// under given id when there is none. It reports whether chat was created.
+func (s *Storage) GetOrCreateDirectChat(ctx context.Context, id, userId, peerId string) (models.Chat, bool, error) {
+	const op = "storage.postgres.GetOrCreateDirectChat"
+
+	var chat models.Chat
+	sqlStr := `WITH chat AS (
+				INSERT INTO chat.chats
+				(id, name, description, owner_id, chat_image_url, participants_id, image_expire_time, type)
+				VALUES ($1, '', '', '', '', ARRAY[$2, $3], $4, $5)
+				ON CONFLICT ((LEAST(participants_id[1], participants_id[2])), (GREATEST(participants_id[1], participants_id[2])))
+				WHERE type = 'direct' AND deleted_at IS NULL
+				DO NOTHING
+				RETURNING id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				SELECT id, $6, jsonb_build_object('user_id', $2::text) FROM chat
+			)
+			SELECT id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			FROM chat`
+	err := s.conn(ctx).QueryRow(
+		ctx,
+		sqlStr,
+		id,
+		userId,
+		peerId,
+		time.Time{},
+		models.ChatTypeDirect,
+		models.OutboxChatCreated,
+	).Scan(
+		&chat.ID,
+		&chat.Name,
+		&chat.Description,
+		&chat.ChatImageUrl,
+		&chat.ChatOwnerId,
+		&chat.ParticipantsId,
+		&chat.ImageExpireTime,
+		&chat.AvatarHash,
+		&chat.Version,
+		&chat.Type,
+		&chat.SubscribersCount,
+		&chat.Public,
+	)
+	if err == nil {
+		return chat, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.Chat{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// conflicting insert waits for the other transaction,
+	// so existing chat is visible to the next statement
+	sqlStr = `SELECT id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			FROM chat.chats
+			WHERE type = $3 AND deleted_at IS NULL
+			AND LEAST(participants_id[1], participants_id[2]) = LEAST($1::text, $2::text)
+			AND GREATEST(participants_id[1], participants_id[2]) = GREATEST($1::text, $2::text)`
+	err = s.conn(ctx).QueryRow(ctx, sqlStr, userId, peerId, models.ChatTypeDirect).Scan(
+		&chat.ID,
+		&chat.Name,
+		&chat.Description,
+		&chat.ChatImageUrl,
+		&chat.ChatOwnerId,
+		&chat.ParticipantsId,
+		&chat.ImageExpireTime,
+		&chat.AvatarHash,
+		&chat.Version,
+		&chat.Type,
+		&chat.SubscribersCount,
+		&chat.Public,
+	)
+	if err != nil {
+		return models.Chat{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chat, false, nil
+}
+
+// Subscribe adds user to channel subscribers on behalf of actor without
+// owner check, callers must authorize it. Subscribing twice is not an
+// error, current chat is returned.
+func (s *Storage) Subscribe(ctx context.Context, chatId, userId, actorId string) (models.Chat, error) {
+	const op = "storage.postgres.Subscribe"
+
+	var chat models.Chat
+	sqlStr := `WITH subscribed AS (
+				INSERT INTO chat.channel_subscribers (chat_id, user_id)
+				SELECT id, $2 FROM chat.chats
+				WHERE id = $1 AND type = 'channel' AND deleted_at IS NULL
+				ON CONFLICT (chat_id, user_id) DO NOTHING
+				RETURNING chat_id
+			), updated AS (
+				UPDATE chat.chats
+				SET subscribers_count = subscribers_count + 1, version = version + 1
+				WHERE id IN (SELECT chat_id FROM subscribed)
+				RETURNING id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				SELECT id, $4, jsonb_build_object('user_id', $3::text, 'participant_id', $2::text) FROM updated
+			)
+			SELECT id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			FROM updated`
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, chatId, userId, actorId, models.OutboxParticipantAdded).Scan(
+		&chat.ID,
+		&chat.Name,
+		&chat.Description,
+		&chat.ChatImageUrl,
+		&chat.ChatOwnerId,
+		&chat.ParticipantsId,
+		&chat.ImageExpireTime,
+		&chat.AvatarHash,
+		&chat.Version,
+		&chat.Type,
+		&chat.SubscribersCount,
+		&chat.Public,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			chat, err = s.GetChat(ctx, chatId)
+			if err != nil {
+				return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+			}
+			return chat, nil
+		}
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chat, nil
+}
+
+func (s *Storage) IsSubscribed(ctx context.Context, chatId, userId string) (bool, error) {
+	const op = "storage.postgres.IsSubscribed"
+
+	var subscribed bool
+	sql := `SELECT EXISTS(SELECT 1 FROM chat.channel_subscribers WHERE chat_id = $1 AND user_id = $2)`
+	err := s.conn(ctx).QueryRow(ctx, sql, chatId, userId).Scan(&subscribed)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return subscribed, nil
+}
+
+// GetSubscribers returns up to limit subscribers of the channel ordered
+// by user id and greater than afterUserId.
+func (s *Storage) GetSubscribers(ctx context.Context, chatId, afterUserId string, limit int) ([]string, error) {
+	const op = "storage.postgres.GetSubscribers"
+
+	sql := `SELECT user_id
+			FROM chat.channel_subscribers
+			WHERE chat_id = $1 AND user_id > $2
+			ORDER BY user_id
+			LIMIT $3`
+	rows, err := s.conn(ctx).Query(ctx, sql, chatId, afterUserId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var subscribers []string
+	for rows.Next() {
+		var userId string
+		err = rows.Scan(&userId)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		subscribers = append(subscribers, userId)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return subscribers, nil
+}
+
+// SetChatVisibility lists chat in public directory or removes it from there.
+// Only owner can change visibility, direct chats are never public.
+func (s *Storage) SetChatVisibility(ctx context.Context, userId, chatId string, public bool) (models.Chat, error) {
+	const op = "storage.postgres.SetChatVisibility"
+
+	var chat models.Chat
+	sqlStr := `WITH updated AS (
+				UPDATE chat.chats
+				SET is_public = $3, version = version + 1
+				WHERE id = $1 AND owner_id = $2 AND type <> 'direct' AND deleted_at IS NULL
+				RETURNING id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			), outbox AS (
+				INSERT INTO chat.outbox (chat_id, kind, payload)
+				SELECT id, $4, jsonb_build_object('user_id', $2::text) FROM updated
+			)
+			SELECT id, name, description, chat_image_url, owner_id, participants_id, image_expire_time, avatar_hash, version, type, subscribers_count, is_public
+			FROM updated`
+	err := s.conn(ctx).QueryRow(ctx, sqlStr, chatId, userId, public, models.OutboxChatUpdated).Scan(
+		&chat.ID,
+		&chat.Name,
+		&chat.Description,
+		&chat.ChatImageUrl,
+		&chat.ChatOwnerId,
+		&chat.ParticipantsId,
+		&chat.ImageExpireTime,
+		&chat.AvatarHash,
+		&chat.Version,
+		&chat.Type,
+		&chat.SubscribersCount,
+		&chat.Public,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Chat{}, fmt.Errorf("%s: %w", op, storage.ErrChatNotFound)
+		}
+		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chat, nil
+}
+
+// SearchPublicChats returns public chats matching web search style query,
+// best matches by name first.
+func (s *Storage) SearchPublicChats(ctx context.Context, query string, offset, limit int) ([]models.PublicChat, error) {
+	const op = "storage.postgres.SearchPublicChats"
+
+	sql := `SELECT id, name, description, chat_image_url, image_expire_time, avatar_hash, type,
+				cardinality(participants_id) + subscribers_count
+			FROM chat.chats, websearch_to_tsquery('simple', $1) AS query
+			WHERE is_public AND deleted_at IS NULL AND search_vector @@ query
+			ORDER BY ts_rank(search_vector, query) DESC, id
+			LIMIT $2 OFFSET $3`
+	rows, err := s.conn(ctx).Query(ctx, sql, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var chats []models.PublicChat
+	for rows.Next() {
+		var chat models.PublicChat
+		err = rows.Scan(
+			&chat.ID,
+			&chat.Name,
+			&chat.Description,
+			&chat.ChatImageUrl,
+			&chat.ImageExpireTime,
+			&chat.AvatarHash,
+			&chat.Type,
+			&chat.MembersCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		chats = append(chats, chat)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chats, nil
+}
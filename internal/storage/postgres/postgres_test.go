@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/AlexMickh/speak-chat/internal/models"
 	"github.com/AlexMickh/speak-chat/internal/storage"
+	"github.com/AlexMickh/speak-chat/internal/worker/gc"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -25,6 +28,7 @@ func TestStorage_SaveChat(t *testing.T) {
 		description    string
 		chatImageUrl   string
 		imageExireTime time.Time
+		avatarHash     string
 		chatOwnerId    string
 	}
 
@@ -83,7 +87,9 @@ func TestStorage_SaveChat(t *testing.T) {
 				tt.args.description,
 				tt.args.chatImageUrl,
 				tt.args.imageExireTime,
+				tt.args.avatarHash,
 				tt.args.chatOwnerId,
+				models.ChatTypeGroup,
 			); err != nil {
 				if !errors.Is(err, tt.wantErr) {
 					t.Errorf("Storage.SaveChat() error = %v, wantErr %v", err, tt.wantErr)
@@ -115,6 +121,8 @@ func TestStorage_GetChat(t *testing.T) {
 		ChatImageUrl:    "eflrkdnhl",
 		ImageExpireTime: time.Time{},
 		ChatOwnerId:     uuid.NewString(),
+		Version:         1,
+		Type:            models.ChatTypeGroup,
 		ParticipantsId:  []string{uuid.NewString(), uuid.NewString(), uuid.NewString()},
 	}
 
@@ -290,6 +298,8 @@ func TestStorage_UpdateChatInfo(t *testing.T) {
 		description    string
 		chatImageUrl   string
 		imageExireTime time.Time
+		avatarHash     string
+		version        int64
 	}
 
 	pool := initStorage()
@@ -344,6 +354,7 @@ func TestStorage_UpdateChatInfo(t *testing.T) {
 				description:    "",
 				chatImageUrl:   "",
 				imageExireTime: time.Time{},
+				version:        1,
 			},
 			want: models.Chat{
 				ID:              chat.ID,
@@ -352,6 +363,8 @@ func TestStorage_UpdateChatInfo(t *testing.T) {
 				ChatImageUrl:    chat.ChatImageUrl,
 				ImageExpireTime: chat.ImageExpireTime,
 				ChatOwnerId:     chat.ChatOwnerId,
+				Version:         2,
+				Type:            models.ChatTypeGroup,
 				ParticipantsId:  chat.ParticipantsId,
 			},
 			wantErr: nil,
@@ -369,6 +382,7 @@ func TestStorage_UpdateChatInfo(t *testing.T) {
 				description:    "changed",
 				chatImageUrl:   "",
 				imageExireTime: time.Time{},
+				version:        0,
 			},
 			want: models.Chat{
 				ID:              chat.ID,
@@ -377,6 +391,8 @@ func TestStorage_UpdateChatInfo(t *testing.T) {
 				ChatImageUrl:    chat.ChatImageUrl,
 				ImageExpireTime: chat.ImageExpireTime,
 				ChatOwnerId:     chat.ChatOwnerId,
+				Version:         3,
+				Type:            models.ChatTypeGroup,
 				ParticipantsId:  chat.ParticipantsId,
 			},
 			wantErr: nil,
@@ -394,6 +410,7 @@ func TestStorage_UpdateChatInfo(t *testing.T) {
 				description:    "",
 				chatImageUrl:   "changed",
 				imageExireTime: time.Time{},
+				version:        3,
 			},
 			want: models.Chat{
 				ID:              chat.ID,
@@ -402,10 +419,30 @@ func TestStorage_UpdateChatInfo(t *testing.T) {
 				ChatImageUrl:    "changed",
 				ImageExpireTime: time.Time{},
 				ChatOwnerId:     chat.ChatOwnerId,
+				Version:         4,
+				Type:            models.ChatTypeGroup,
 				ParticipantsId:  chat.ParticipantsId,
 			},
 			wantErr: nil,
 		},
+		{
+			name: "stale version",
+			fields: fields{
+				db: pool,
+			},
+			args: args{
+				ctx:            context.Background(),
+				userId:         chat.ChatOwnerId,
+				chatId:         chat.ID,
+				name:           "stale",
+				description:    "",
+				chatImageUrl:   "",
+				imageExireTime: time.Time{},
+				version:        1,
+			},
+			want:    models.Chat{},
+			wantErr: storage.ErrVersionConflict,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -420,6 +457,8 @@ func TestStorage_UpdateChatInfo(t *testing.T) {
 				tt.args.description,
 				tt.args.chatImageUrl,
 				tt.args.imageExireTime,
+				tt.args.avatarHash,
+				tt.args.version,
 			)
 			if err != nil {
 				if !errors.Is(err, tt.wantErr) {
@@ -437,6 +476,369 @@ func TestStorage_UpdateChatInfo(t *testing.T) {
 	}
 }
 
+func TestStorage_WithTx(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+	ownerId := uuid.NewString()
+	outerId := uuid.NewString()
+	innerId := uuid.NewString()
+	failedId := uuid.NewString()
+	errInner := errors.New("inner failed")
+
+	err := s.WithTx(ctx, func(ctx context.Context) error {
+		err := s.SaveChat(ctx, outerId, "outer", "outer", "url", time.Time{}, "", ownerId, models.ChatTypeGroup)
+		if err != nil {
+			return err
+		}
+
+		// savepoint is rolled back, outer transaction goes on
+		err = s.WithTx(ctx, func(ctx context.Context) error {
+			err := s.SaveChat(ctx, innerId, "inner", "inner", "url", time.Time{}, "", ownerId, models.ChatTypeGroup)
+			if err != nil {
+				return err
+			}
+			return errInner
+		})
+		if !errors.Is(err, errInner) {
+			return fmt.Errorf("nested WithTx() error = %v, want %v", err, errInner)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Storage.WithTx() error = %v", err)
+	}
+
+	err = s.WithTx(ctx, func(ctx context.Context) error {
+		err := s.SaveChat(ctx, failedId, "failed", "failed", "url", time.Time{}, "", ownerId, models.ChatTypeGroup)
+		if err != nil {
+			return err
+		}
+		return errInner
+	})
+	if !errors.Is(err, errInner) {
+		t.Errorf("Storage.WithTx() error = %v, want %v", err, errInner)
+	}
+
+	tests := []struct {
+		id      string
+		wantErr error
+	}{
+		{id: outerId, wantErr: nil},
+		{id: innerId, wantErr: storage.ErrChatNotFound},
+		{id: failedId, wantErr: storage.ErrChatNotFound},
+	}
+	for _, tt := range tests {
+		_, err := s.GetChat(ctx, tt.id)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Storage.GetChat(%s) error = %v, want %v", tt.id, err, tt.wantErr)
+		}
+	}
+
+	_, _ = pool.Exec(ctx, "DELETE FROM chat.chats WHERE owner_id = $1", ownerId)
+}
+
+func TestStorage_SoftDelete(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+	ownerId := uuid.NewString()
+	chatId := uuid.NewString()
+
+	err := s.SaveChat(ctx, chatId, "chat", "chat", "url", time.Time{}, "", ownerId, models.ChatTypeGroup)
+	if err != nil {
+		t.Fatalf("Storage.SaveChat() error = %v", err)
+	}
+
+	deleteChat := func() {
+		ch := make(chan error, 1)
+		s.DeleteChat(ctx, ownerId, chatId, 0, ch)
+		if err := <-ch; err != nil {
+			t.Fatalf("Storage.DeleteChat() error = %v", err)
+		}
+	}
+
+	deleteChat()
+	if _, err = s.GetChat(ctx, chatId); !errors.Is(err, storage.ErrChatNotFound) {
+		t.Errorf("Storage.GetChat() of deleted chat error = %v, want %v", err, storage.ErrChatNotFound)
+	}
+
+	_, err = s.RestoreChat(ctx, uuid.NewString(), chatId, time.Hour)
+	if !errors.Is(err, storage.ErrChatNotFound) {
+		t.Errorf("Storage.RestoreChat() by stranger error = %v, want %v", err, storage.ErrChatNotFound)
+	}
+
+	restored, err := s.RestoreChat(ctx, ownerId, chatId, time.Hour)
+	if err != nil {
+		t.Fatalf("Storage.RestoreChat() error = %v", err)
+	}
+	if restored.ID != chatId {
+		t.Errorf("Storage.RestoreChat() id = %v, want %v", restored.ID, chatId)
+	}
+
+	deleteChat()
+	_, err = s.RestoreChat(ctx, ownerId, chatId, 0)
+	if !errors.Is(err, storage.ErrChatNotFound) {
+		t.Errorf("Storage.RestoreChat() after window error = %v, want %v", err, storage.ErrChatNotFound)
+	}
+
+	purged, err := s.PurgeDeletedChats(ctx, 0, 100)
+	if err != nil {
+		t.Fatalf("Storage.PurgeDeletedChats() error = %v", err)
+	}
+	if purged == 0 {
+		t.Errorf("Storage.PurgeDeletedChats() = 0, want deleted chat purged")
+	}
+}
+
+func TestStorage_FailOutboxKeepsRetryZone(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+
+	var id int64
+	err := pool.QueryRow(
+		ctx,
+		"INSERT INTO chat.outbox (chat_id, kind) VALUES ($1, $2) RETURNING id",
+		uuid.NewString(),
+		models.OutboxChatUpdated,
+	).Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to insert outbox entry: %v", err)
+	}
+	defer func() {
+		_, _ = pool.Exec(ctx, "DELETE FROM chat.outbox WHERE id = $1", id)
+	}()
+
+	// retry times of relays far from UTC must mean the same instant
+	east := time.FixedZone("east", 10*60*60)
+	west := time.FixedZone("west", -10*60*60)
+
+	tests := []struct {
+		name        string
+		retryAt     time.Time
+		wantClaimed bool
+	}{
+		{
+			name:        "future retry in west zone is not due",
+			retryAt:     time.Now().Add(time.Hour).In(west),
+			wantClaimed: false,
+		},
+		{
+			name:        "past retry in east zone is due",
+			retryAt:     time.Now().Add(-time.Minute).In(east),
+			wantClaimed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.FailOutbox(ctx, id, "failed", tt.retryAt)
+			if err != nil {
+				t.Fatalf("Storage.FailOutbox() error = %v", err)
+			}
+
+			entries, err := s.ClaimOutbox(ctx, 1000, time.Millisecond)
+			if err != nil {
+				t.Fatalf("Storage.ClaimOutbox() error = %v", err)
+			}
+
+			claimed := slices.ContainsFunc(entries, func(entry models.OutboxEntry) bool {
+				return entry.ID == id
+			})
+			if claimed != tt.wantClaimed {
+				t.Errorf("entry claimed = %v, want %v", claimed, tt.wantClaimed)
+			}
+		})
+	}
+}
+
+func TestStorage_FailWebhookDeliveryKeepsRetryZone(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+	chatId := uuid.NewString()
+
+	webhook, err := s.SaveWebhook(ctx, models.Webhook{
+		ID:        uuid.NewString(),
+		ChatId:    chatId,
+		Url:       "https://hooks.example.com",
+		Secret:    "secret",
+		CreatedBy: "owner",
+	})
+	if err != nil {
+		t.Fatalf("Storage.SaveWebhook() error = %v", err)
+	}
+	defer func() {
+		_, _ = pool.Exec(ctx, "DELETE FROM chat.webhooks WHERE id = $1", webhook.ID)
+	}()
+
+	err = s.EnqueueWebhookDeliveries(ctx, chatId, "1", models.OutboxChatUpdated, []byte(`{}`))
+	if err != nil {
+		t.Fatalf("Storage.EnqueueWebhookDeliveries() error = %v", err)
+	}
+
+	var id int64
+	err = pool.QueryRow(ctx, "SELECT id FROM chat.webhook_deliveries WHERE webhook_id = $1", webhook.ID).Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to get delivery: %v", err)
+	}
+
+	east := time.FixedZone("east", 10*60*60)
+	west := time.FixedZone("west", -10*60*60)
+
+	tests := []struct {
+		name        string
+		retryAt     time.Time
+		wantClaimed bool
+	}{
+		{
+			name:        "future retry in west zone is not due",
+			retryAt:     time.Now().Add(time.Hour).In(west),
+			wantClaimed: false,
+		},
+		{
+			name:        "past retry in east zone is due",
+			retryAt:     time.Now().Add(-time.Minute).In(east),
+			wantClaimed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.FailWebhookDelivery(ctx, id, "failed", tt.retryAt)
+			if err != nil {
+				t.Fatalf("Storage.FailWebhookDelivery() error = %v", err)
+			}
+
+			deliveries, err := s.ClaimWebhookDeliveries(ctx, 1000, time.Millisecond)
+			if err != nil {
+				t.Fatalf("Storage.ClaimWebhookDeliveries() error = %v", err)
+			}
+
+			claimed := slices.ContainsFunc(deliveries, func(delivery models.WebhookDelivery) bool {
+				return delivery.ID == id
+			})
+			if claimed != tt.wantClaimed {
+				t.Errorf("delivery claimed = %v, want %v", claimed, tt.wantClaimed)
+			}
+		})
+	}
+}
+
+func TestStorage_AuditEntries(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+	chatId := uuid.NewString()
+
+	err := s.CreateAuditPartition(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("Storage.CreateAuditPartition() error = %v", err)
+	}
+
+	actions := []string{models.AuditChatCreated, models.AuditChatUpdated, models.AuditChatDeleted}
+	for _, action := range actions {
+		err = s.SaveAuditEntry(ctx, models.AuditEntry{
+			ChatId:  chatId,
+			ActorId: "owner",
+			Action:  action,
+			Diff:    map[string]models.AuditChange{"name": {Before: "old", After: "new"}},
+		})
+		if err != nil {
+			t.Fatalf("Storage.SaveAuditEntry() error = %v", err)
+		}
+	}
+
+	firstPage, err := s.GetAuditEntries(ctx, chatId, 0, 2)
+	if err != nil {
+		t.Fatalf("Storage.GetAuditEntries() error = %v", err)
+	}
+	if len(firstPage) != 2 || firstPage[0].Action != models.AuditChatDeleted {
+		t.Fatalf("Storage.GetAuditEntries() = %v, want 2 newest entries", firstPage)
+	}
+	if got := firstPage[0].Diff["name"].After; got != "new" {
+		t.Errorf("Storage.GetAuditEntries() diff after = %v, want %v", got, "new")
+	}
+
+	secondPage, err := s.GetAuditEntries(ctx, chatId, firstPage[1].ID, 2)
+	if err != nil {
+		t.Fatalf("Storage.GetAuditEntries() error = %v", err)
+	}
+	if len(secondPage) != 1 || secondPage[0].Action != models.AuditChatCreated {
+		t.Errorf("Storage.GetAuditEntries() = %v, want oldest entry", secondPage)
+	}
+
+	_, _ = pool.Exec(ctx, "DELETE FROM chat.audit_log WHERE chat_id = $1", chatId)
+}
+
+func TestStorage_CreateAuditPartitionMovesDefaultRows(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+	chatId := uuid.NewString()
+	month := time.Date(2199, time.January, 1, 0, 0, 0, 0, time.UTC)
+	partition := "chat.audit_log_" + month.Format("2006_01")
+	defer func() {
+		_, _ = pool.Exec(ctx, "DROP TABLE IF EXISTS "+partition)
+	}()
+
+	// no partition covers the month yet, so the row lands in the default one
+	_, err := pool.Exec(
+		ctx,
+		`INSERT INTO chat.audit_log (chat_id, actor_id, action, created_at)
+		VALUES ($1, 'owner', $2, $3)`,
+		chatId,
+		models.AuditChatCreated,
+		month.AddDate(0, 0, 3),
+	)
+	if err != nil {
+		t.Fatalf("failed to insert audit entry: %v", err)
+	}
+
+	err = s.CreateAuditPartition(ctx, month)
+	if err != nil {
+		t.Fatalf("Storage.CreateAuditPartition() error = %v", err)
+	}
+
+	var inPartition, inDefault int
+	err = pool.QueryRow(ctx, "SELECT count(*) FROM "+partition+" WHERE chat_id = $1", chatId).Scan(&inPartition)
+	if err != nil {
+		t.Fatalf("failed to count partition rows: %v", err)
+	}
+	err = pool.QueryRow(ctx, "SELECT count(*) FROM chat.audit_log_default WHERE chat_id = $1", chatId).Scan(&inDefault)
+	if err != nil {
+		t.Fatalf("failed to count default partition rows: %v", err)
+	}
+	if inPartition != 1 || inDefault != 0 {
+		t.Errorf("rows in partition = %v, in default = %v, want 1 and 0", inPartition, inDefault)
+	}
+
+	entries, err := s.GetAuditEntries(ctx, chatId, 0, 10)
+	if err != nil {
+		t.Fatalf("Storage.GetAuditEntries() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Storage.GetAuditEntries() = %v, want moved entry", entries)
+	}
+
+	err = s.CreateAuditPartition(ctx, month)
+	if err != nil {
+		t.Errorf("Storage.CreateAuditPartition() second call error = %v", err)
+	}
+}
+
 func initStorage() *pgxpool.Pool {
 	connString := fmt.Sprintf(
 		"postgres://%s:%s@%s:%d/%s?sslmode=disable&pool_max_conns=%d&pool_min_conns=%d",
@@ -453,3 +855,458 @@ func initStorage() *pgxpool.Pool {
 
 	return pool
 }
+
+func TestStorage_Invites(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+	ownerId := uuid.NewString()
+	chatId := uuid.NewString()
+
+	err := s.SaveChat(ctx, chatId, "chat", "chat", "url", time.Time{}, "", ownerId, models.ChatTypeGroup)
+	if err != nil {
+		t.Fatalf("Storage.SaveChat() error = %v", err)
+	}
+
+	invite, err := s.SaveInvite(ctx, models.Invite{
+		Code:      uuid.NewString(),
+		ChatId:    chatId,
+		CreatedBy: ownerId,
+		MaxUses:   1,
+	})
+	if err != nil {
+		t.Fatalf("Storage.SaveInvite() error = %v", err)
+	}
+
+	got, err := s.ConsumeInvite(ctx, invite.Code)
+	if err != nil {
+		t.Fatalf("Storage.ConsumeInvite() error = %v", err)
+	}
+	if got != chatId {
+		t.Errorf("Storage.ConsumeInvite() = %v, want %v", got, chatId)
+	}
+
+	_, err = s.ConsumeInvite(ctx, invite.Code)
+	if !errors.Is(err, storage.ErrInviteNotFound) {
+		t.Errorf("Storage.ConsumeInvite() used up error = %v, want %v", err, storage.ErrInviteNotFound)
+	}
+
+	invites, err := s.GetInvites(ctx, chatId)
+	if err != nil {
+		t.Fatalf("Storage.GetInvites() error = %v", err)
+	}
+	if len(invites) != 0 {
+		t.Errorf("Storage.GetInvites() = %v, want no usable invites", invites)
+	}
+
+	participantId := uuid.NewString()
+	chat, err := s.JoinChat(ctx, chatId, participantId, participantId)
+	if err != nil {
+		t.Fatalf("Storage.JoinChat() error = %v", err)
+	}
+	if !reflect.DeepEqual(chat.ParticipantsId, []string{ownerId, participantId}) {
+		t.Errorf("Storage.JoinChat() participants = %v, want %v", chat.ParticipantsId, []string{ownerId, participantId})
+	}
+
+	err = s.RevokeInvite(ctx, chatId, invite.Code)
+	if err != nil {
+		t.Errorf("Storage.RevokeInvite() error = %v", err)
+	}
+	err = s.RevokeInvite(ctx, chatId, invite.Code)
+	if !errors.Is(err, storage.ErrInviteNotFound) {
+		t.Errorf("Storage.RevokeInvite() twice error = %v, want %v", err, storage.ErrInviteNotFound)
+	}
+}
+
+func TestStorage_JoinRequests(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+	ownerId := uuid.NewString()
+	chatId := uuid.NewString()
+
+	err := s.SaveChat(ctx, chatId, "chat", "chat", "url", time.Time{}, "", ownerId, models.ChatTypeGroup)
+	if err != nil {
+		t.Fatalf("Storage.SaveChat() error = %v", err)
+	}
+
+	request := models.JoinRequest{ID: uuid.NewString(), ChatId: chatId, UserId: uuid.NewString(), Message: "hi"}
+	_, err = s.SaveJoinRequest(ctx, request)
+	if err != nil {
+		t.Fatalf("Storage.SaveJoinRequest() error = %v", err)
+	}
+
+	duplicate := request
+	duplicate.ID = uuid.NewString()
+	_, err = s.SaveJoinRequest(ctx, duplicate)
+	if !errors.Is(err, storage.ErrJoinRequestExists) {
+		t.Errorf("Storage.SaveJoinRequest() duplicate error = %v, want %v", err, storage.ErrJoinRequestExists)
+	}
+
+	pending, err := s.GetPendingJoinRequests(ctx, chatId)
+	if err != nil {
+		t.Fatalf("Storage.GetPendingJoinRequests() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != request.ID {
+		t.Errorf("Storage.GetPendingJoinRequests() = %v, want request %v", pending, request.ID)
+	}
+
+	decided, err := s.DecideJoinRequest(ctx, chatId, request.ID, models.JoinRequestRejected, ownerId)
+	if err != nil {
+		t.Fatalf("Storage.DecideJoinRequest() error = %v", err)
+	}
+	if decided.Status != models.JoinRequestRejected || decided.DecidedBy != ownerId {
+		t.Errorf("Storage.DecideJoinRequest() = %+v, want rejected by %v", decided, ownerId)
+	}
+
+	_, err = s.DecideJoinRequest(ctx, chatId, request.ID, models.JoinRequestApproved, ownerId)
+	if !errors.Is(err, storage.ErrJoinRequestNotFound) {
+		t.Errorf("Storage.DecideJoinRequest() twice error = %v, want %v", err, storage.ErrJoinRequestNotFound)
+	}
+}
+
+func TestStorage_Moderation(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+	ownerId := uuid.NewString()
+	chatId := uuid.NewString()
+	userId := uuid.NewString()
+
+	err := s.SaveChat(ctx, chatId, "chat", "chat", "url", time.Time{}, "", ownerId, models.ChatTypeGroup)
+	if err != nil {
+		t.Fatalf("Storage.SaveChat() error = %v", err)
+	}
+	_, err = s.AddParticipant(ctx, ownerId, chatId, userId)
+	if err != nil {
+		t.Fatalf("Storage.AddParticipant() error = %v", err)
+	}
+
+	chat, err := s.BanUser(ctx, models.Ban{ChatId: chatId, UserId: userId, Reason: "spam", BannedBy: ownerId})
+	if err != nil {
+		t.Fatalf("Storage.BanUser() error = %v", err)
+	}
+	if !reflect.DeepEqual(chat.ParticipantsId, []string{ownerId}) {
+		t.Errorf("Storage.BanUser() participants = %v, want %v", chat.ParticipantsId, []string{ownerId})
+	}
+
+	banned, err := s.IsBanned(ctx, chatId, userId)
+	if err != nil || !banned {
+		t.Errorf("Storage.IsBanned() = %v, %v, want true", banned, err)
+	}
+
+	err = s.UnbanUser(ctx, chatId, userId, ownerId)
+	if err != nil {
+		t.Errorf("Storage.UnbanUser() error = %v", err)
+	}
+	err = s.UnbanUser(ctx, chatId, userId, ownerId)
+	if !errors.Is(err, storage.ErrBanNotFound) {
+		t.Errorf("Storage.UnbanUser() twice error = %v, want %v", err, storage.ErrBanNotFound)
+	}
+
+	_, err = s.MuteUser(ctx, models.Mute{
+		ChatId:  chatId,
+		UserId:  userId,
+		MutedBy: ownerId,
+		Until:   time.Now().UTC().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Storage.MuteUser() error = %v", err)
+	}
+	if _, err = s.GetMute(ctx, chatId, userId); err != nil {
+		t.Errorf("Storage.GetMute() error = %v", err)
+	}
+
+	err = s.UnmuteUser(ctx, chatId, userId, ownerId)
+	if err != nil {
+		t.Errorf("Storage.UnmuteUser() error = %v", err)
+	}
+	_, err = s.GetMute(ctx, chatId, userId)
+	if !errors.Is(err, storage.ErrMuteNotFound) {
+		t.Errorf("Storage.GetMute() after unmute error = %v, want %v", err, storage.ErrMuteNotFound)
+	}
+}
+
+func TestStorage_GetOrCreateDirectChat(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+	userId := uuid.NewString()
+	peerId := uuid.NewString()
+
+	chat, created, err := s.GetOrCreateDirectChat(ctx, uuid.NewString(), userId, peerId)
+	if err != nil {
+		t.Fatalf("Storage.GetOrCreateDirectChat() error = %v", err)
+	}
+	if !created || chat.Type != models.ChatTypeDirect || chat.ChatOwnerId != "" {
+		t.Errorf("Storage.GetOrCreateDirectChat() = %+v, %v, want new direct chat without owner", chat, created)
+	}
+
+	// reversed pair resolves to the same chat
+	got, created, err := s.GetOrCreateDirectChat(ctx, uuid.NewString(), peerId, userId)
+	if err != nil {
+		t.Fatalf("Storage.GetOrCreateDirectChat() error = %v", err)
+	}
+	if created || got.ID != chat.ID {
+		t.Errorf("Storage.GetOrCreateDirectChat() = %v, %v, want existing chat %v", got.ID, created, chat.ID)
+	}
+}
+
+func TestStorage_Channels(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+	ownerId := uuid.NewString()
+	chatId := uuid.NewString()
+	userId := uuid.NewString()
+
+	err := s.SaveChat(ctx, chatId, "channel", "channel", "url", time.Time{}, "", ownerId, models.ChatTypeChannel)
+	if err != nil {
+		t.Fatalf("Storage.SaveChat() error = %v", err)
+	}
+
+	for range 2 {
+		chat, err := s.Subscribe(ctx, chatId, userId, userId)
+		if err != nil {
+			t.Fatalf("Storage.Subscribe() error = %v", err)
+		}
+		if chat.SubscribersCount != 1 || !reflect.DeepEqual(chat.ParticipantsId, []string{ownerId}) {
+			t.Errorf("Storage.Subscribe() = %+v, want one subscriber outside of participants", chat)
+		}
+	}
+
+	subscribers, err := s.GetSubscribers(ctx, chatId, "", 10)
+	if err != nil {
+		t.Fatalf("Storage.GetSubscribers() error = %v", err)
+	}
+	if !reflect.DeepEqual(subscribers, []string{userId}) {
+		t.Errorf("Storage.GetSubscribers() = %v, want %v", subscribers, []string{userId})
+	}
+
+	chats, err := s.GetAllUserChats(ctx, userId)
+	if err != nil || len(chats) != 1 || chats[0].ID != chatId {
+		t.Errorf("Storage.GetAllUserChats() = %v, %v, want subscribed channel", chats, err)
+	}
+
+	chat, err := s.BanUser(ctx, models.Ban{ChatId: chatId, UserId: userId, BannedBy: ownerId})
+	if err != nil {
+		t.Fatalf("Storage.BanUser() error = %v", err)
+	}
+	if chat.SubscribersCount != 0 {
+		t.Errorf("Storage.BanUser() subscribers = %v, want 0", chat.SubscribersCount)
+	}
+}
+
+func TestStorage_SearchPublicChats(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+	ownerId := uuid.NewString()
+	word := "w" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	publicId := uuid.NewString()
+	err := s.SaveChat(ctx, publicId, word, "public", "url", time.Time{}, "", ownerId, models.ChatTypeGroup)
+	if err != nil {
+		t.Fatalf("Storage.SaveChat() error = %v", err)
+	}
+	privateId := uuid.NewString()
+	err = s.SaveChat(ctx, privateId, word, "private", "url", time.Time{}, "", ownerId, models.ChatTypeGroup)
+	if err != nil {
+		t.Fatalf("Storage.SaveChat() error = %v", err)
+	}
+
+	_, err = s.SetChatVisibility(ctx, uuid.NewString(), publicId, true)
+	if !errors.Is(err, storage.ErrChatNotFound) {
+		t.Errorf("Storage.SetChatVisibility() by stranger error = %v, want %v", err, storage.ErrChatNotFound)
+	}
+
+	chat, err := s.SetChatVisibility(ctx, ownerId, publicId, true)
+	if err != nil {
+		t.Fatalf("Storage.SetChatVisibility() error = %v", err)
+	}
+	if !chat.Public {
+		t.Errorf("Storage.SetChatVisibility() public = %v, want true", chat.Public)
+	}
+
+	chats, err := s.SearchPublicChats(ctx, word, 0, 10)
+	if err != nil {
+		t.Fatalf("Storage.SearchPublicChats() error = %v", err)
+	}
+	if len(chats) != 1 || chats[0].ID != publicId || chats[0].MembersCount != 1 {
+		t.Errorf("Storage.SearchPublicChats() = %+v, want only public chat", chats)
+	}
+}
+
+type gcObjects struct {
+	objects []models.AvatarObject
+	removed []string
+}
+
+func (o *gcObjects) ListAvatars(ctx context.Context) ([]models.AvatarObject, error) {
+	return o.objects, nil
+}
+
+func (o *gcObjects) RemoveAvatar(ctx context.Context, key string) error {
+	o.removed = append(o.removed, key)
+	return nil
+}
+
+func TestStorage_AcquireAvatarWaitsForGC(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// orphan row is left by a chat that changed its avatar,
+		// legacy objects were uploaded before content addressing
+		hasRow bool
+	}{
+		{
+			name:   "orphan row",
+			hasRow: true,
+		},
+		{
+			name:   "object without row",
+			hasRow: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash := uuid.NewString()
+			defer func() {
+				_, _ = pool.Exec(ctx, "DELETE FROM chat.avatars WHERE hash = $1", hash)
+			}()
+
+			if tt.hasRow {
+				_, err := pool.Exec(ctx, "INSERT INTO chat.avatars (hash, size, ref_count) VALUES ($1, 10, 0)", hash)
+				if err != nil {
+					t.Fatalf("failed to insert avatar: %v", err)
+				}
+			}
+
+			acquired := make(chan bool, 1)
+			err := s.WithTx(ctx, func(ctx context.Context) error {
+				deleted, err := s.DeleteAvatar(ctx, hash)
+				if err != nil {
+					return err
+				}
+				if !deleted {
+					t.Fatalf("Storage.DeleteAvatar() = false, want true")
+				}
+
+				// upload of the same content comes while gc removes the blob
+				go func() {
+					created, err := s.AcquireAvatar(context.Background(), hash, 10)
+					if err != nil {
+						t.Errorf("Storage.AcquireAvatar() error = %v", err)
+					}
+					acquired <- created
+				}()
+
+				select {
+				case <-acquired:
+					t.Error("Storage.AcquireAvatar() returned before gc committed")
+				case <-time.After(200 * time.Millisecond):
+				}
+
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Storage.WithTx() error = %v", err)
+			}
+
+			if created := <-acquired; !created {
+				t.Errorf("Storage.AcquireAvatar() created = false, want blob uploaded again")
+			}
+		})
+	}
+}
+
+func TestStorage_AvatarVersionsSurviveGC(t *testing.T) {
+	pool := initStorage()
+	defer pool.Close()
+
+	s := New(pool)
+	ctx := context.Background()
+	ownerId := uuid.NewString()
+	chatId := uuid.NewString()
+	legacy, first, second, orphan := uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()
+
+	// version of an avatar uploaded before content addressing has no avatars row
+	_, err := pool.Exec(
+		ctx,
+		"INSERT INTO chat.chat_avatar_versions (chat_id, avatar_hash, created_by) VALUES ($1, $2, $3)",
+		chatId, legacy, ownerId,
+	)
+	if err != nil {
+		t.Fatalf("failed to insert legacy version: %v", err)
+	}
+
+	err = s.SaveChat(ctx, chatId, "chat", "chat", "url", time.Time{}, first, ownerId, models.ChatTypeGroup)
+	if err != nil {
+		t.Fatalf("Storage.SaveChat() error = %v", err)
+	}
+	if _, err = s.AcquireAvatar(ctx, first, 10); err != nil {
+		t.Fatalf("Storage.AcquireAvatar() error = %v", err)
+	}
+	_, err = s.UpdateChatInfo(ctx, ownerId, chatId, "", "", "url", time.Time{}, second, 0)
+	if err != nil {
+		t.Fatalf("Storage.UpdateChatInfo() error = %v", err)
+	}
+	if _, err = s.AcquireAvatar(ctx, second, 10); err != nil {
+		t.Fatalf("Storage.AcquireAvatar() error = %v", err)
+	}
+
+	versions, err := s.GetAvatarVersions(ctx, chatId)
+	if err != nil {
+		t.Fatalf("Storage.GetAvatarVersions() error = %v", err)
+	}
+	if len(versions) != 3 || versions[0].AvatarHash != second {
+		t.Fatalf("Storage.GetAvatarVersions() = %+v, want 3 versions, newest first", versions)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	objects := &gcObjects{objects: []models.AvatarObject{
+		{Key: legacy, LastModified: old},
+		{Key: first, LastModified: old},
+		{Key: second, LastModified: old},
+		{Key: orphan, LastModified: old},
+	}}
+	_, err = gc.New(objects, s, 0, false).Run(ctx)
+	if err != nil {
+		t.Fatalf("GC.Run() error = %v", err)
+	}
+	if !reflect.DeepEqual(objects.removed, []string{orphan}) {
+		t.Errorf("GC removed %v, want only %v", objects.removed, orphan)
+	}
+
+	for _, version := range versions[1:] {
+		got, err := s.GetAvatarVersion(ctx, chatId, version.ID)
+		if err != nil {
+			t.Fatalf("Storage.GetAvatarVersion() error = %v", err)
+		}
+
+		chat, err := s.UpdateChatInfo(ctx, ownerId, chatId, "", "", "url", time.Time{}, got.AvatarHash, 0)
+		if err != nil {
+			t.Fatalf("Storage.UpdateChatInfo() error = %v", err)
+		}
+		if chat.AvatarHash != version.AvatarHash {
+			t.Errorf("restored avatar = %v, want %v", chat.AvatarHash, version.AvatarHash)
+		}
+	}
+}
@@ -2,71 +2,83 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
 	"time"
 
 	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/internal/storage"
 	"github.com/redis/go-redis/v9"
 )
 
 type Client interface {
-	HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
-	HGetAll(ctx context.Context, key string) *redis.MapStringStringCmd
+	TxPipeline() redis.Pipeliner
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
-	Pipeline() redis.Pipeliner
-	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
-	RPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
-	LRange(ctx context.Context, key string, start int64, stop int64) *redis.StringSliceCmd
-	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
 }
 
+const invalidationChannel = "chat:invalidate"
+
+// keyVersion is a part of every key, so a change of cache layout
+// never reads entries written by the old one.
+const keyVersion = "v6"
+
 type Redis struct {
 	rdb Client
 	cfg struct {
-		db         int
-		expiration time.Duration
+		db                 int
+		expiration         time.Duration
+		negativeExpiration time.Duration
+		earlyRefreshDelta  time.Duration
 	}
 }
 
-func New(rdb Client, db int, expiration time.Duration) *Redis {
+func New(
+	rdb Client,
+	db int,
+	expiration time.Duration,
+	negativeExpiration time.Duration,
+	earlyRefreshDelta time.Duration,
+) *Redis {
 	return &Redis{
 		rdb: rdb,
 		cfg: struct {
-			db         int
-			expiration time.Duration
+			db                 int
+			expiration         time.Duration
+			negativeExpiration time.Duration
+			earlyRefreshDelta  time.Duration
 		}{
-			db:         db,
-			expiration: expiration,
+			db:                 db,
+			expiration:         expiration,
+			negativeExpiration: negativeExpiration,
+			earlyRefreshDelta:  earlyRefreshDelta,
 		},
 	}
 }
 
+// SaveChat replaces cached chat with given one. All keys are rewritten
+// in one MULTI/EXEC transaction, so saving is atomic and idempotent.
 func (r *Redis) SaveChat(ctx context.Context, chat models.Chat) error {
 	const op = "storage.redis.SaveChat"
 
-	pipeline := r.rdb.Pipeline()
-
-	err := pipeline.HSet(ctx, chat.ID, chat).Err()
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
-	}
+	chatKey := chatKey(chat.ID)
+	participantsKey := participantsKey(chat.ID)
 
-	err = pipeline.Expire(ctx, chat.ID, r.cfg.expiration).Err()
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+	tx := r.rdb.TxPipeline()
+	tx.Del(ctx, missingKey(chat.ID), chatKey, participantsKey)
+	tx.HSet(ctx, chatKey, chat)
+	tx.Expire(ctx, chatKey, r.cfg.expiration)
+	if len(chat.ParticipantsId) > 0 {
+		tx.SAdd(ctx, participantsKey, toAny(chat.ParticipantsId)...)
+		tx.Expire(ctx, participantsKey, r.cfg.expiration)
 	}
 
-	err = r.rdb.RPush(ctx, chat.ID+"&part", chat.ParticipantsId).Err()
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
-	err = pipeline.Expire(ctx, chat.ID+"&part", r.cfg.expiration).Err()
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
-	}
-
-	_, err = pipeline.Exec(ctx)
+	_, err := tx.Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -74,30 +86,58 @@ func (r *Redis) SaveChat(ctx context.Context, chat models.Chat) error {
 	return nil
 }
 
+// GetChat returns cached chat. It returns storage.ErrChatNotFound for chats
+// that are cached as nonexistent and storage.ErrCacheMiss when chat is not
+// cached or is about to expire and should be refreshed by this caller.
 func (r *Redis) GetChat(ctx context.Context, id string) (models.Chat, error) {
 	const op = "storage.redis.GetChat"
 
-	var chat models.Chat
-	err := r.rdb.HGetAll(ctx, id).Scan(&chat)
-	if err != nil {
+	chatKey := chatKey(id)
+
+	tx := r.rdb.TxPipeline()
+	missing := tx.Exists(ctx, missingKey(id))
+	fields := tx.HGetAll(ctx, chatKey)
+	ttl := tx.PTTL(ctx, chatKey)
+	participants := tx.SMembers(ctx, participantsKey(id))
+
+	_, err := tx.Exec(ctx)
+	if err != nil && !errors.Is(err, redis.Nil) {
 		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = r.rdb.Expire(ctx, id, r.cfg.expiration).Err()
-	if err != nil {
-		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
+	if missing.Val() > 0 {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, storage.ErrChatNotFound)
+	}
+	if len(fields.Val()) == 0 || r.expiresEarly(ttl.Val()) {
+		return models.Chat{}, fmt.Errorf("%s: %w", op, storage.ErrCacheMiss)
 	}
 
-	chat.ParticipantsId, err = r.rdb.LRange(ctx, chat.ID+"&part", 0, -1).Result()
+	var chat models.Chat
+	err = fields.Scan(&chat)
 	if err != nil {
 		return models.Chat{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	chat.ID = id
+	chat.ParticipantsId = participants.Val()
+	slices.Sort(chat.ParticipantsId)
 
 	return chat, nil
 }
 
+// SaveMissingChat caches the fact that chat does not exist,
+// so lookups of unknown ids do not hit the database every time.
+func (r *Redis) SaveMissingChat(ctx context.Context, id string) error {
+	const op = "storage.redis.SaveMissingChat"
+
+	err := r.rdb.Set(ctx, missingKey(id), 1, r.cfg.negativeExpiration).Err()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 func (r *Redis) UpdateChat(ctx context.Context, chat models.Chat) error {
 	const op = "storage.redis.UpdateChat"
 
@@ -109,10 +149,10 @@ func (r *Redis) UpdateChat(ctx context.Context, chat models.Chat) error {
 	return nil
 }
 
-func (r *Redis) AddParticipant(ctx context.Context, chatId, participantId string) error {
-	const op = "storage.redis.AddParticipant"
+func (r *Redis) DeleteChat(ctx context.Context, chatId string) error {
+	const op = "storage.redis.DeleteChat"
 
-	err := r.rdb.RPush(ctx, chatId+"&part", participantId).Err()
+	err := r.rdb.Del(ctx, chatKey(chatId), participantsKey(chatId)).Err()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -120,13 +160,73 @@ func (r *Redis) AddParticipant(ctx context.Context, chatId, participantId string
 	return nil
 }
 
-func (r *Redis) DeleteChat(ctx context.Context, chatId string) error {
-	const op = "storage.redis.DeleteChat"
+// PublishInvalidation tells all replicas to drop their local copy of the chat.
+func (r *Redis) PublishInvalidation(ctx context.Context, chatId string) error {
+	const op = "storage.redis.PublishInvalidation"
 
-	err := r.rdb.Del(ctx, chatId).Err()
+	err := r.rdb.Publish(ctx, invalidationChannel, chatId).Err()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
+
+// ListenInvalidations calls fn with chat id of every invalidation message
+// until ctx is done.
+func (r *Redis) ListenInvalidations(ctx context.Context, fn func(chatId string)) error {
+	const op = "storage.redis.ListenInvalidations"
+
+	pubsub := r.rdb.Subscribe(ctx, invalidationChannel)
+	defer pubsub.Close()
+
+	// wait for subscription confirmation, so errors are not lost
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			fn(msg.Payload)
+		}
+	}
+}
+
+// expiresEarly implements probabilistic early expiration (XFetch).
+// The closer the key is to its ttl, the more likely a single reader treats
+// it as expired and refreshes it, before all readers miss at once.
+func (r *Redis) expiresEarly(ttl time.Duration) bool {
+	if r.cfg.earlyRefreshDelta <= 0 || ttl < 0 {
+		return false
+	}
+
+	return float64(ttl)+float64(r.cfg.earlyRefreshDelta)*math.Log(rand.Float64()) <= 0
+}
+
+func chatKey(id string) string {
+	return "chat:" + keyVersion + ":" + id
+}
+
+func participantsKey(id string) string {
+	return chatKey(id) + ":participants"
+}
+
+func missingKey(id string) string {
+	return chatKey(id) + ":missing"
+}
+
+func toAny(values []string) []any {
+	res := make([]any, 0, len(values))
+	for _, value := range values {
+		res = append(res, value)
+	}
+	return res
+}
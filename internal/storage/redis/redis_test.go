@@ -0,0 +1,452 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/internal/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedis_SaveChat(t *testing.T) {
+	ctx := context.Background()
+	r, fake := initRedis(t)
+
+	chat := models.Chat{
+		ID:             "chat",
+		Name:           "name",
+		Description:    "description",
+		ChatImageUrl:   "url",
+		AvatarHash:     "hash",
+		ChatOwnerId:    "owner",
+		Version:        3,
+		Type:           models.ChatTypeGroup,
+		ParticipantsId: []string{"owner", "user"},
+	}
+
+	tests := []struct {
+		name string
+		save []models.Chat
+		want models.Chat
+	}{
+		{
+			name: "good case",
+			save: []models.Chat{chat},
+			want: chat,
+		},
+		{
+			name: "saving twice does not duplicate participants",
+			save: []models.Chat{chat, chat},
+			want: chat,
+		},
+		{
+			name: "resave replaces participants",
+			save: []models.Chat{
+				chat,
+				{ID: "chat", Name: "changed", ChatOwnerId: "owner", ParticipantsId: []string{"owner"}},
+			},
+			want: models.Chat{ID: "chat", Name: "changed", ChatOwnerId: "owner", ParticipantsId: []string{"owner"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.flush()
+
+			for _, chat := range tt.save {
+				if err := r.SaveChat(ctx, chat); err != nil {
+					t.Fatalf("Redis.SaveChat() error = %v", err)
+				}
+			}
+
+			got, err := r.GetChat(ctx, tt.want.ID)
+			if err != nil {
+				t.Fatalf("Redis.GetChat() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Redis.GetChat() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedis_GetChat(t *testing.T) {
+	ctx := context.Background()
+	r, fake := initRedis(t)
+
+	tests := []struct {
+		name    string
+		prepare func()
+		wantErr error
+	}{
+		{
+			name:    "not cached",
+			prepare: func() {},
+			wantErr: storage.ErrCacheMiss,
+		},
+		{
+			name: "cached as missing",
+			prepare: func() {
+				_ = r.SaveMissingChat(ctx, "chat")
+			},
+			wantErr: storage.ErrChatNotFound,
+		},
+		{
+			name: "save clears missing mark",
+			prepare: func() {
+				_ = r.SaveMissingChat(ctx, "chat")
+				_ = r.SaveChat(ctx, models.Chat{ID: "chat", ParticipantsId: []string{"owner"}})
+			},
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.flush()
+			tt.prepare()
+
+			_, err := r.GetChat(ctx, "chat")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Redis.GetChat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedis_DeleteChat(t *testing.T) {
+	ctx := context.Background()
+	r, fake := initRedis(t)
+
+	err := r.SaveChat(ctx, models.Chat{ID: "chat", ParticipantsId: []string{"owner", "user"}})
+	if err != nil {
+		t.Fatalf("Redis.SaveChat() error = %v", err)
+	}
+
+	err = r.DeleteChat(ctx, "chat")
+	if err != nil {
+		t.Fatalf("Redis.DeleteChat() error = %v", err)
+	}
+
+	if keys := fake.keys(); len(keys) != 0 {
+		t.Errorf("keys left after delete: %v", keys)
+	}
+}
+
+func initRedis(t *testing.T) (*Redis, *fakeServer) {
+	t.Helper()
+
+	fake := newFakeServer(t)
+	rdb := redis.NewClient(&redis.Options{
+		Addr:            fake.addr(),
+		Protocol:        2,
+		DisableIdentity: true,
+	})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	return New(rdb, 0, time.Hour, time.Minute, 0), fake
+}
+
+// fakeServer is an in-process Redis speaking RESP2. It implements only
+// commands used by this package, with MULTI/EXEC executed atomically.
+type fakeServer struct {
+	ln net.Listener
+
+	mu   sync.Mutex
+	data map[string]*fakeValue
+}
+
+type fakeValue struct {
+	str       string
+	hash      map[string]string
+	set       map[string]struct{}
+	expiresAt time.Time
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	f := &fakeServer{
+		ln:   ln,
+		data: make(map[string]*fakeValue),
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	return f
+}
+
+func (f *fakeServer) addr() string {
+	return f.ln.Addr().String()
+}
+
+func (f *fakeServer) flush() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	clear(f.data)
+}
+
+func (f *fakeServer) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var keys []string
+	for key := range f.data {
+		if f.lookup(key) != nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (f *fakeServer) expire(key string, ttl time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if v := f.lookup(key); v != nil {
+		v.expiresAt = time.Now().Add(ttl)
+	}
+}
+
+func (f *fakeServer) ttl(key string) time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	v := f.lookup(key)
+	if v == nil || v.expiresAt.IsZero() {
+		return 0
+	}
+	return time.Until(v.expiresAt)
+}
+
+func (f *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	rd := bufio.NewReader(conn)
+	var queue [][]string
+	inTx := false
+
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			return
+		}
+
+		var reply string
+		switch name := strings.ToUpper(args[0]); {
+		case name == "MULTI":
+			inTx = true
+			queue = nil
+			reply = "+OK\r\n"
+		case name == "EXEC":
+			f.mu.Lock()
+			reply = fmt.Sprintf("*%d\r\n", len(queue))
+			for _, cmd := range queue {
+				reply += f.exec(cmd)
+			}
+			f.mu.Unlock()
+			inTx = false
+		case name == "DISCARD":
+			inTx = false
+			reply = "+OK\r\n"
+		case inTx:
+			queue = append(queue, args)
+			reply = "+QUEUED\r\n"
+		default:
+			f.mu.Lock()
+			reply = f.exec(args)
+			f.mu.Unlock()
+		}
+
+		if _, err = io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// exec runs one command, f.mu must be held.
+func (f *fakeServer) exec(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "DEL":
+		var n int
+		for _, key := range args[1:] {
+			if f.lookup(key) != nil {
+				n++
+			}
+			delete(f.data, key)
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	case "EXISTS":
+		var n int
+		for _, key := range args[1:] {
+			if f.lookup(key) != nil {
+				n++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	case "SET":
+		v := &fakeValue{str: args[2]}
+		if len(args) == 5 {
+			amount, _ := strconv.Atoi(args[4])
+			unit := time.Second
+			if strings.EqualFold(args[3], "px") {
+				unit = time.Millisecond
+			}
+			v.expiresAt = time.Now().Add(time.Duration(amount) * unit)
+		}
+		f.data[args[1]] = v
+		return "+OK\r\n"
+	case "HSET":
+		v := f.lookup(args[1])
+		if v == nil {
+			v = &fakeValue{hash: make(map[string]string)}
+			f.data[args[1]] = v
+		}
+		var n int
+		for i := 2; i+1 < len(args); i += 2 {
+			if _, ok := v.hash[args[i]]; !ok {
+				n++
+			}
+			v.hash[args[i]] = args[i+1]
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	case "HGETALL":
+		v := f.lookup(args[1])
+		if v == nil {
+			return "*0\r\n"
+		}
+		reply := fmt.Sprintf("*%d\r\n", 2*len(v.hash))
+		for field, value := range v.hash {
+			reply += bulk(field) + bulk(value)
+		}
+		return reply
+	case "SADD":
+		v := f.lookup(args[1])
+		if v == nil {
+			v = &fakeValue{set: make(map[string]struct{})}
+			f.data[args[1]] = v
+		}
+		var n int
+		for _, member := range args[2:] {
+			if _, ok := v.set[member]; !ok {
+				n++
+			}
+			v.set[member] = struct{}{}
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	case "SMEMBERS":
+		v := f.lookup(args[1])
+		if v == nil {
+			return "*0\r\n"
+		}
+		reply := fmt.Sprintf("*%d\r\n", len(v.set))
+		for member := range v.set {
+			reply += bulk(member)
+		}
+		return reply
+	case "EXPIRE", "PEXPIRE":
+		v := f.lookup(args[1])
+		if v == nil {
+			return ":0\r\n"
+		}
+		amount, _ := strconv.Atoi(args[2])
+		unit := time.Second
+		if strings.EqualFold(args[0], "pexpire") {
+			unit = time.Millisecond
+		}
+		v.expiresAt = time.Now().Add(time.Duration(amount) * unit)
+		return ":1\r\n"
+	case "PTTL":
+		v := f.lookup(args[1])
+		switch {
+		case v == nil:
+			return ":-2\r\n"
+		case v.expiresAt.IsZero():
+			return ":-1\r\n"
+		}
+		return fmt.Sprintf(":%d\r\n", time.Until(v.expiresAt).Milliseconds())
+	case "PUBLISH":
+		return ":0\r\n"
+	}
+
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+// lookup returns live value of the key, f.mu must be held.
+func (f *fakeServer) lookup(key string) *fakeValue {
+	v, ok := f.data[key]
+	if !ok {
+		return nil
+	}
+	if !v.expiresAt.IsZero() && time.Now().After(v.expiresAt) {
+		delete(f.data, key)
+		return nil
+	}
+	return v
+}
+
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("bad command header %q", line)
+	}
+
+	args := make([]string, 0, n)
+	for range n {
+		line, err = rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("bad bulk header %q", line)
+		}
+
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+
+		args = append(args, string(buf[:size]))
+	}
+
+	return args, nil
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
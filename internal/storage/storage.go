@@ -3,6 +3,15 @@ package storage
 import "errors"
 
 var (
-	ErrChatAlreadyExists = errors.New("chat already exists")
-	ErrChatNotFound      = errors.New("chat with this id does not found")
+	ErrChatAlreadyExists     = errors.New("chat already exists")
+	ErrChatNotFound          = errors.New("chat with this id does not found")
+	ErrAvatarVersionNotFound = errors.New("avatar version does not found")
+	ErrCacheMiss             = errors.New("chat is not cached")
+	ErrVersionConflict       = errors.New("chat was modified concurrently")
+	ErrWebhookNotFound       = errors.New("webhook does not found")
+	ErrInviteNotFound        = errors.New("invite does not found or is no longer valid")
+	ErrJoinRequestExists     = errors.New("join request is already pending")
+	ErrJoinRequestNotFound   = errors.New("join request does not found")
+	ErrBanNotFound           = errors.New("ban does not found")
+	ErrMuteNotFound          = errors.New("mute does not found")
 )
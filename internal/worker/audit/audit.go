@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type Storage interface {
+	CreateAuditPartition(ctx context.Context, month time.Time) error
+}
+
+// Partitioner creates monthly audit log partitions ahead of time,
+// so entries do not end up in the default partition.
+type Partitioner struct {
+	storage Storage
+	ahead   int
+}
+
+func New(storage Storage, ahead int) *Partitioner {
+	return &Partitioner{
+		storage: storage,
+		ahead:   ahead,
+	}
+}
+
+// Run creates partitions for current month and ahead months after it.
+func (p *Partitioner) Run(ctx context.Context) error {
+	const op = "worker.audit.Run"
+
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= p.ahead; i++ {
+		err := p.storage.CreateAuditPartition(ctx, month.AddDate(0, i, 0))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// Start creates partitions right away and then every interval until ctx is done.
+func (p *Partitioner) Start(ctx context.Context, interval time.Duration) {
+	const op = "worker.audit.Start"
+
+	ctx = logger.GetFromCtx(ctx).With(ctx, zap.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := p.Run(ctx)
+		if err != nil {
+			logger.GetFromCtx(ctx).Error(ctx, "failed to create audit partitions", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errStorage = errors.New("storage is down")
+
+// fakeStorage records created months and fails on failAt call if err is set.
+type fakeStorage struct {
+	months []time.Time
+	failAt int
+	err    error
+}
+
+func (f *fakeStorage) CreateAuditPartition(ctx context.Context, month time.Time) error {
+	if f.err != nil && len(f.months) == f.failAt {
+		return f.err
+	}
+
+	f.months = append(f.months, month)
+	return nil
+}
+
+func TestPartitioner_Run(t *testing.T) {
+	now := time.Now().UTC()
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		ahead      int
+		failAt     int
+		err        error
+		wantMonths []time.Time
+		wantErr    error
+	}{
+		{
+			name:       "current month only",
+			wantMonths: []time.Time{current},
+		},
+		{
+			name:       "current and ahead months",
+			ahead:      2,
+			wantMonths: []time.Time{current, current.AddDate(0, 1, 0), current.AddDate(0, 2, 0)},
+		},
+		{
+			name:       "storage error stops creation",
+			ahead:      2,
+			failAt:     1,
+			err:        errStorage,
+			wantMonths: []time.Time{current},
+			wantErr:    errStorage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStorage{failAt: tt.failAt, err: tt.err}
+			p := New(store, tt.ahead)
+
+			err := p.Run(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Partitioner.Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(store.months) != len(tt.wantMonths) {
+				t.Fatalf("CreateAuditPartition() months = %v, want %v", store.months, tt.wantMonths)
+			}
+			for i, month := range tt.wantMonths {
+				if !store.months[i].Equal(month) {
+					t.Errorf("CreateAuditPartition() month %d = %v, want %v", i, store.months[i], month)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type Objects interface {
+	ListAvatars(ctx context.Context) ([]models.AvatarObject, error)
+	RemoveAvatar(ctx context.Context, key string) error
+}
+
+type Storage interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+	IsAvatarInUse(ctx context.Context, key string) (bool, error)
+	DeleteAvatar(ctx context.Context, hash string) (bool, error)
+}
+
+// Report describes the result of one collection pass.
+type Report struct {
+	Scanned        int
+	Orphans        []string
+	ReclaimedBytes int64
+	DryRun         bool
+}
+
+// GC deletes avatar objects that are not referenced by any chat.
+type GC struct {
+	objects     Objects
+	storage     Storage
+	gracePeriod time.Duration
+	dryRun      bool
+}
+
+func New(objects Objects, storage Storage, gracePeriod time.Duration, dryRun bool) *GC {
+	return &GC{
+		objects:     objects,
+		storage:     storage,
+		gracePeriod: gracePeriod,
+		dryRun:      dryRun,
+	}
+}
+
+// Run makes one collection pass. Objects younger than grace period are
+// skipped, so uploads of chats that are being created right now survive.
+// In dry-run mode orphans are only reported.
+func (g *GC) Run(ctx context.Context) (Report, error) {
+	const op = "worker.gc.Run"
+
+	report := Report{DryRun: g.dryRun}
+
+	objects, err := g.objects.ListAvatars(ctx)
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deadline := time.Now().Add(-g.gracePeriod)
+	for _, object := range objects {
+		report.Scanned++
+
+		if object.LastModified.After(deadline) {
+			continue
+		}
+
+		inUse, err := g.storage.IsAvatarInUse(ctx, object.Key)
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", op, err)
+		}
+		if inUse {
+			continue
+		}
+
+		if !g.dryRun {
+			removed, err := g.remove(ctx, object.Key)
+			if err != nil {
+				return report, fmt.Errorf("%s: %w", op, err)
+			}
+			if !removed {
+				continue
+			}
+		}
+
+		report.Orphans = append(report.Orphans, object.Key)
+		report.ReclaimedBytes += object.Size
+	}
+
+	return report, nil
+}
+
+// remove deletes avatar row and then its blob in one transaction.
+// Uploads of the same content wait for the transaction, so they either
+// keep the row, and the blob stays, or upload the blob again after it
+// was removed. Failed removal rolls the row back.
+func (g *GC) remove(ctx context.Context, key string) (bool, error) {
+	var removed bool
+	err := g.storage.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		removed, err = g.storage.DeleteAvatar(ctx, key)
+		if err != nil || !removed {
+			return err
+		}
+
+		return g.objects.RemoveAvatar(ctx, key)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return removed, nil
+}
+
+// Start runs collection every interval until ctx is done.
+func (g *GC) Start(ctx context.Context, interval time.Duration) {
+	const op = "worker.gc.Start"
+
+	ctx = logger.GetFromCtx(ctx).With(ctx, zap.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := g.Run(ctx)
+			if err != nil {
+				logger.GetFromCtx(ctx).Error(ctx, "failed to collect orphaned avatars", zap.Error(err))
+			}
+
+			logger.GetFromCtx(ctx).Info(
+				ctx,
+				"orphaned avatars collected",
+				zap.Bool("dry_run", report.DryRun),
+				zap.Int("scanned", report.Scanned),
+				zap.Int("orphans", len(report.Orphans)),
+				zap.Int64("reclaimed_bytes", report.ReclaimedBytes),
+			)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package gc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+)
+
+var (
+	errStorage = errors.New("storage is down")
+	errObjects = errors.New("objects are down")
+)
+
+type fakeObjects struct {
+	objects []models.AvatarObject
+	store   *fakeStorage
+	err     error
+	removed []string
+	// removed after row deletion was committed
+	outsideTx []string
+}
+
+func (f *fakeObjects) ListAvatars(ctx context.Context) ([]models.AvatarObject, error) {
+	return f.objects, nil
+}
+
+func (f *fakeObjects) RemoveAvatar(ctx context.Context, key string) error {
+	if !f.store.inTx {
+		f.outsideTx = append(f.outsideTx, key)
+	}
+	if f.err != nil {
+		return f.err
+	}
+	f.removed = append(f.removed, key)
+	return nil
+}
+
+// fakeStorage keeps deleted rows pending until transaction commits.
+type fakeStorage struct {
+	inUse []string
+	// reacquired keys are referenced again between check and delete
+	reacquired []string
+	err        error
+	inTx       bool
+	pending    []string
+	deleted    []string
+}
+
+func (f *fakeStorage) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	f.inTx = true
+	err := fn(ctx)
+	f.inTx = false
+
+	if err == nil {
+		f.deleted = append(f.deleted, f.pending...)
+	}
+	f.pending = nil
+	return err
+}
+
+func (f *fakeStorage) IsAvatarInUse(ctx context.Context, key string) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	return slices.Contains(f.inUse, key), nil
+}
+
+func (f *fakeStorage) DeleteAvatar(ctx context.Context, hash string) (bool, error) {
+	if slices.Contains(f.reacquired, hash) {
+		return false, nil
+	}
+	f.pending = append(f.pending, hash)
+	return true, nil
+}
+
+func TestGC_Run(t *testing.T) {
+	old := time.Now().Add(-2 * time.Hour)
+
+	tests := []struct {
+		name        string
+		objects     []models.AvatarObject
+		inUse       []string
+		reacquired  []string
+		storageErr  error
+		removeErr   error
+		dryRun      bool
+		wantRemoved []string
+		wantErr     error
+	}{
+		{
+			name:    "in use object is kept",
+			objects: []models.AvatarObject{{Key: "used", Size: 10, LastModified: old}},
+			inUse:   []string{"used"},
+		},
+		{
+			name:    "object inside grace period is kept",
+			objects: []models.AvatarObject{{Key: "fresh", Size: 10, LastModified: time.Now()}},
+		},
+		{
+			name:        "unreferenced object past grace period is removed",
+			objects:     []models.AvatarObject{{Key: "orphan", Size: 10, LastModified: old}},
+			wantRemoved: []string{"orphan"},
+		},
+		{
+			name:       "object referenced again before delete is kept",
+			objects:    []models.AvatarObject{{Key: "raced", Size: 10, LastModified: old}},
+			reacquired: []string{"raced"},
+		},
+		{
+			name:    "dry run removes nothing",
+			objects: []models.AvatarObject{{Key: "orphan", Size: 10, LastModified: old}},
+			dryRun:  true,
+		},
+		{
+			name:      "failed blob removal keeps the row",
+			objects:   []models.AvatarObject{{Key: "orphan", Size: 10, LastModified: old}},
+			removeErr: errObjects,
+			wantErr:   errObjects,
+		},
+		{
+			name:       "storage error stops deletion",
+			objects:    []models.AvatarObject{{Key: "orphan", Size: 10, LastModified: old}},
+			storageErr: errStorage,
+			wantErr:    errStorage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStorage{inUse: tt.inUse, reacquired: tt.reacquired, err: tt.storageErr}
+			objects := &fakeObjects{objects: tt.objects, store: store, err: tt.removeErr}
+			g := New(objects, store, time.Hour, tt.dryRun)
+
+			_, err := g.Run(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GC.Run() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !slices.Equal(objects.removed, tt.wantRemoved) {
+				t.Errorf("removed objects = %v, want %v", objects.removed, tt.wantRemoved)
+			}
+			if !slices.Equal(store.deleted, tt.wantRemoved) {
+				t.Errorf("deleted rows = %v, want %v", store.deleted, tt.wantRemoved)
+			}
+			// uploads of the same content wait for the row delete to commit,
+			// so the blob has to be gone by then
+			if len(objects.outsideTx) != 0 {
+				t.Errorf("objects removed after row delete commit = %v", objects.outsideTx)
+			}
+		})
+	}
+}
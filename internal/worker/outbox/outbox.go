@@ -0,0 +1,225 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/internal/storage"
+	"github.com/AlexMickh/speak-chat/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type Storage interface {
+	ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEntry, error)
+	CompleteOutbox(ctx context.Context, id int64) error
+	FailOutbox(ctx context.Context, id int64, reason string, retryAt time.Time) error
+	GetChat(ctx context.Context, id string) (models.Chat, error)
+}
+
+type Cash interface {
+	UpdateChat(ctx context.Context, chat models.Chat) error
+	DeleteChat(ctx context.Context, chatId string) error
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// Relay applies side effects of committed chat changes recorded in outbox.
+// Entries are delivered at least once, so every step must be idempotent:
+// cache is always rebuilt from current database state instead of
+// replaying the change itself, and events carry outbox id for dedup.
+type Relay struct {
+	storage    Storage
+	cash       Cash
+	publishers []Publisher
+	batchSize  int
+	lease      time.Duration
+	maxBackoff time.Duration
+}
+
+// New creates relay. Every event is passed to all publishers.
+func New(
+	storage Storage,
+	cash Cash,
+	publishers []Publisher,
+	batchSize int,
+	lease time.Duration,
+	maxBackoff time.Duration,
+) *Relay {
+	return &Relay{
+		storage:    storage,
+		cash:       cash,
+		publishers: publishers,
+		batchSize:  batchSize,
+		lease:      lease,
+		maxBackoff: maxBackoff,
+	}
+}
+
+// Run relays one batch of due entries and returns how many were delivered.
+// Failed entries are rescheduled with exponential backoff.
+func (r *Relay) Run(ctx context.Context) (int, error) {
+	const op = "worker.outbox.Run"
+
+	entries, err := r.storage.ClaimOutbox(ctx, r.batchSize, r.lease)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	delivered := 0
+	for _, entry := range entries {
+		err = r.apply(ctx, entry)
+		if err != nil {
+			logger.GetFromCtx(ctx).Error(
+				ctx,
+				"failed to relay outbox entry",
+				zap.Int64("id", entry.ID),
+				zap.String("kind", entry.Kind),
+				zap.Int("attempts", entry.Attempts),
+				zap.Error(err),
+			)
+
+			retryAt := time.Now().Add(r.backoff(entry.Attempts))
+			err = r.storage.FailOutbox(ctx, entry.ID, err.Error(), retryAt)
+			if err != nil {
+				return delivered, fmt.Errorf("%s: %w", op, err)
+			}
+			continue
+		}
+
+		err = r.storage.CompleteOutbox(ctx, entry.ID)
+		if err != nil {
+			return delivered, fmt.Errorf("%s: %w", op, err)
+		}
+		delivered++
+	}
+
+	return delivered, nil
+}
+
+// Start relays entries every interval until ctx is done.
+// Full batches are followed immediately by the next one.
+func (r *Relay) Start(ctx context.Context, interval time.Duration) {
+	const op = "worker.outbox.Start"
+
+	ctx = logger.GetFromCtx(ctx).With(ctx, zap.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				delivered, err := r.Run(ctx)
+				if err != nil {
+					logger.GetFromCtx(ctx).Error(ctx, "failed to relay outbox", zap.Error(err))
+					break
+				}
+				if delivered < r.batchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+func (r *Relay) apply(ctx context.Context, entry models.OutboxEntry) error {
+	const op = "worker.outbox.apply"
+
+	chat, found, err := r.syncCache(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(r.publishers) == 0 {
+		return nil
+	}
+
+	event := models.Event{
+		ID:         strconv.FormatInt(entry.ID, 10),
+		Type:       entry.Kind,
+		Version:    models.EventSchemaVersion,
+		ChatId:     entry.ChatId,
+		Data:       entry.Payload,
+		OccurredAt: entry.CreatedAt,
+	}
+	if found {
+		event.Chat = &models.EventChat{
+			ID:               chat.ID,
+			Name:             chat.Name,
+			Description:      chat.Description,
+			AvatarHash:       chat.AvatarHash,
+			OwnerId:          chat.ChatOwnerId,
+			ParticipantsId:   chat.ParticipantsId,
+			Version:          chat.Version,
+			Type:             chat.Type,
+			SubscribersCount: chat.SubscribersCount,
+			Public:           chat.Public,
+		}
+	}
+
+	// entry is retried as a whole, publishers that already got
+	// the event drop the duplicate by its id
+	for _, publisher := range r.publishers {
+		err = publisher.Publish(ctx, event)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// syncCache makes cached chat match the database and returns current chat.
+// False is returned when chat no longer exists.
+func (r *Relay) syncCache(ctx context.Context, entry models.OutboxEntry) (models.Chat, bool, error) {
+	const op = "worker.outbox.syncCache"
+
+	if entry.Kind == models.OutboxChatDeleted {
+		err := r.cash.DeleteChat(ctx, entry.ChatId)
+		if err != nil {
+			return models.Chat{}, false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return models.Chat{}, false, nil
+	}
+
+	chat, err := r.storage.GetChat(ctx, entry.ChatId)
+	if err != nil {
+		if !errors.Is(err, storage.ErrChatNotFound) {
+			return models.Chat{}, false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		// chat was deleted after this change, its own entry is relayed later
+		err = r.cash.DeleteChat(ctx, entry.ChatId)
+		if err != nil {
+			return models.Chat{}, false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return models.Chat{}, false, nil
+	}
+
+	err = r.cash.UpdateChat(ctx, chat)
+	if err != nil {
+		return models.Chat{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chat, true, nil
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	backoff := time.Second << min(attempts, 30)
+	if backoff > r.maxBackoff {
+		return r.maxBackoff
+	}
+
+	return backoff
+}
@@ -0,0 +1,159 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/broker/memory"
+	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/internal/storage"
+	"github.com/AlexMickh/speak-chat/pkg/logger"
+)
+
+type fakeStorage struct {
+	entries   []models.OutboxEntry
+	chats     map[string]models.Chat
+	completed []int64
+	failed    []int64
+}
+
+func (f *fakeStorage) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEntry, error) {
+	return f.entries, nil
+}
+
+func (f *fakeStorage) CompleteOutbox(ctx context.Context, id int64) error {
+	f.completed = append(f.completed, id)
+	return nil
+}
+
+func (f *fakeStorage) FailOutbox(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	f.failed = append(f.failed, id)
+	return nil
+}
+
+func (f *fakeStorage) GetChat(ctx context.Context, id string) (models.Chat, error) {
+	chat, ok := f.chats[id]
+	if !ok {
+		return models.Chat{}, storage.ErrChatNotFound
+	}
+	return chat, nil
+}
+
+type fakeCash struct {
+	chats map[string]models.Chat
+	err   error
+}
+
+func (f *fakeCash) UpdateChat(ctx context.Context, chat models.Chat) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.chats[chat.ID] = chat
+	return nil
+}
+
+func (f *fakeCash) DeleteChat(ctx context.Context, chatId string) error {
+	if f.err != nil {
+		return f.err
+	}
+	delete(f.chats, chatId)
+	return nil
+}
+
+func TestRelay_Run(t *testing.T) {
+	ctx := logger.New(context.Background(), []string{"stderr"}, "prod")
+
+	tests := []struct {
+		name          string
+		entry         models.OutboxEntry
+		cashErr       error
+		wantCached    bool
+		wantCompleted bool
+	}{
+		{
+			name:          "updated chat is cached",
+			entry:         models.OutboxEntry{ID: 1, ChatId: "a", Kind: models.OutboxChatUpdated},
+			wantCached:    true,
+			wantCompleted: true,
+		},
+		{
+			name:          "chat deleted later is evicted",
+			entry:         models.OutboxEntry{ID: 1, ChatId: "gone", Kind: models.OutboxChatCreated},
+			wantCompleted: true,
+		},
+		{
+			name:          "deleted chat is evicted",
+			entry:         models.OutboxEntry{ID: 1, ChatId: "a", Kind: models.OutboxChatDeleted},
+			wantCompleted: true,
+		},
+		{
+			name:       "cache failure is retried",
+			entry:      models.OutboxEntry{ID: 1, ChatId: "a", Kind: models.OutboxChatDeleted},
+			cashErr:    errors.New("redis is down"),
+			wantCached: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStorage{
+				entries: []models.OutboxEntry{tt.entry},
+				chats:   map[string]models.Chat{"a": {ID: "a"}},
+			}
+			cash := &fakeCash{
+				chats: map[string]models.Chat{"a": {ID: "a"}, "gone": {ID: "gone"}},
+				err:   tt.cashErr,
+			}
+			publisher := memory.New()
+			r := New(store, cash, []Publisher{publisher}, 10, time.Minute, time.Minute)
+
+			_, err := r.Run(ctx)
+			if err != nil {
+				t.Fatalf("Relay.Run() error = %v", err)
+			}
+
+			if _, ok := cash.chats[tt.entry.ChatId]; ok != tt.wantCached {
+				t.Errorf("chat cached = %v, want %v", ok, tt.wantCached)
+			}
+			if got := len(store.completed) == 1; got != tt.wantCompleted {
+				t.Errorf("entry completed = %v, want %v", got, tt.wantCompleted)
+			}
+			if got := len(store.failed) == 1; got == tt.wantCompleted {
+				t.Errorf("entry failed = %v, want %v", got, !tt.wantCompleted)
+			}
+
+			events := publisher.Events()
+			if got := len(events) == 1; got != tt.wantCompleted {
+				t.Fatalf("event published = %v, want %v", got, tt.wantCompleted)
+			}
+			if tt.wantCompleted {
+				if events[0].ID != "1" || events[0].Type != tt.entry.Kind {
+					t.Errorf("published event = %+v, want id 1 and type %s", events[0], tt.entry.Kind)
+				}
+				if got := events[0].Chat != nil; got != tt.wantCached {
+					t.Errorf("event has chat = %v, want %v", got, tt.wantCached)
+				}
+			}
+		})
+	}
+}
+
+func TestRelay_backoff(t *testing.T) {
+	r := New(nil, nil, nil, 10, time.Minute, 5*time.Minute)
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: time.Second},
+		{attempts: 3, want: 8 * time.Second},
+		{attempts: 20, want: 5 * time.Minute},
+		{attempts: 100, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := r.backoff(tt.attempts); got != tt.want {
+			t.Errorf("Relay.backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
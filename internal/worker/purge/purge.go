@@ -0,0 +1,83 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type Storage interface {
+	PurgeDeletedChats(ctx context.Context, window time.Duration, limit int) (int, error)
+}
+
+var ErrInvalidBatchSize = errors.New("batch size must be positive")
+
+// Purger removes soft deleted chats once their restore window is over.
+// Avatar blobs are left to gc, they may be shared with other chats.
+type Purger struct {
+	storage   Storage
+	window    time.Duration
+	batchSize int
+}
+
+// New rejects non positive batch sizes, Run would never see a short batch
+// with them and loop forever.
+func New(storage Storage, window time.Duration, batchSize int) (*Purger, error) {
+	const op = "worker.purge.New"
+
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("%s: %w: %d", op, ErrInvalidBatchSize, batchSize)
+	}
+
+	return &Purger{
+		storage:   storage,
+		window:    window,
+		batchSize: batchSize,
+	}, nil
+}
+
+// Run purges all expired chats in batches and returns how many were removed.
+func (p *Purger) Run(ctx context.Context) (int, error) {
+	const op = "worker.purge.Run"
+
+	total := 0
+	for {
+		purged, err := p.storage.PurgeDeletedChats(ctx, p.window, p.batchSize)
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", op, err)
+		}
+
+		total += purged
+		if purged < p.batchSize || ctx.Err() != nil {
+			return total, nil
+		}
+	}
+}
+
+// Start runs purge every interval until ctx is done.
+func (p *Purger) Start(ctx context.Context, interval time.Duration) {
+	const op = "worker.purge.Start"
+
+	ctx = logger.GetFromCtx(ctx).With(ctx, zap.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := p.Run(ctx)
+			if err != nil {
+				logger.GetFromCtx(ctx).Error(ctx, "failed to purge deleted chats", zap.Error(err))
+			}
+
+			logger.GetFromCtx(ctx).Info(ctx, "deleted chats purged", zap.Int("purged", purged))
+		}
+	}
+}
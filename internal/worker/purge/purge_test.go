@@ -0,0 +1,93 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errStorage = errors.New("storage is down")
+
+// fakeStorage purges batches one by one and fails after them if err is set.
+type fakeStorage struct {
+	batches []int
+	err     error
+	calls   int
+}
+
+func (f *fakeStorage) PurgeDeletedChats(ctx context.Context, window time.Duration, limit int) (int, error) {
+	f.calls++
+	if len(f.batches) == 0 {
+		return 0, f.err
+	}
+
+	purged := f.batches[0]
+	f.batches = f.batches[1:]
+	return purged, nil
+}
+
+func TestPurger_Run(t *testing.T) {
+	tests := []struct {
+		name      string
+		batches   []int
+		err       error
+		wantTotal int
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "nothing to purge",
+			wantCalls: 1,
+		},
+		{
+			name:      "short batch is the last one",
+			batches:   []int{10, 10, 3},
+			wantTotal: 23,
+			wantCalls: 3,
+		},
+		{
+			name:      "full batch is followed by another pass",
+			batches:   []int{10},
+			wantTotal: 10,
+			wantCalls: 2,
+		},
+		{
+			name:      "storage error stops purge",
+			batches:   []int{10},
+			err:       errStorage,
+			wantTotal: 10,
+			wantCalls: 2,
+			wantErr:   errStorage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStorage{batches: tt.batches, err: tt.err}
+			p, err := New(store, time.Hour, 10)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			total, err := p.Run(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Purger.Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("Purger.Run() = %v, want %v", total, tt.wantTotal)
+			}
+			if store.calls != tt.wantCalls {
+				t.Errorf("PurgeDeletedChats() calls = %v, want %v", store.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, batchSize := range []int{0, -1} {
+		_, err := New(&fakeStorage{}, time.Hour, batchSize)
+		if !errors.Is(err, ErrInvalidBatchSize) {
+			t.Errorf("New(%d) error = %v, want %v", batchSize, err, ErrInvalidBatchSize)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Headers of every delivery. Receivers verify signature of
+// "<timestamp>.<body>" and reject old timestamps to stop replays.
+const (
+	HeaderEventId   = "X-Speak-Event-Id"
+	HeaderEventType = "X-Speak-Event"
+	HeaderTimestamp = "X-Speak-Timestamp"
+	HeaderSignature = "X-Speak-Signature"
+)
+
+var (
+	ErrUnexpectedStatus = errors.New("webhook responded with unexpected status")
+	ErrForbiddenAddress = errors.New("webhook address is not public")
+)
+
+type Storage interface {
+	EnqueueWebhookDeliveries(ctx context.Context, chatId, eventId, eventType string, payload []byte) error
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	CompleteWebhookDelivery(ctx context.Context, id int64) error
+	FailWebhookDelivery(ctx context.Context, id int64, reason string, retryAt time.Time) error
+	DeadLetterWebhookDelivery(ctx context.Context, id int64, reason string) error
+}
+
+// Dispatcher delivers chat events to webhooks registered by chat owners.
+// Failed deliveries are retried with exponential backoff and moved
+// to dead letters after maxAttempts.
+type Dispatcher struct {
+	storage     Storage
+	client      *http.Client
+	batchSize   int
+	lease       time.Duration
+	maxAttempts int
+	maxBackoff  time.Duration
+}
+
+func New(
+	storage Storage,
+	client *http.Client,
+	batchSize int,
+	lease time.Duration,
+	maxAttempts int,
+	maxBackoff time.Duration,
+) *Dispatcher {
+	return &Dispatcher{
+		storage:     storage,
+		client:      client,
+		batchSize:   batchSize,
+		lease:       lease,
+		maxAttempts: maxAttempts,
+		maxBackoff:  maxBackoff,
+	}
+}
+
+// NewClient returns http client for deliveries. It refuses to connect to
+// loopback, private, link-local and unspecified addresses after DNS
+// resolution, so webhooks can not reach internal services, and returns
+// redirects as they are instead of following them.
+func NewClient(timeout time.Duration) *http.Client {
+	return newClient(timeout, checkAddress)
+}
+
+func newClient(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: control,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// proxy would be dialed instead of the webhook and hide its address
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func checkAddress(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	ip = ip.Unmap()
+	if ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+
+	return nil
+}
+
+// Publish schedules event delivery to webhooks of its chat.
+// It is called by outbox relay, so events are enqueued at least once
+// and duplicates are dropped by event id.
+func (d *Dispatcher) Publish(ctx context.Context, event models.Event) error {
+	const op = "worker.webhook.Publish"
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = d.storage.EnqueueWebhookDeliveries(ctx, event.ChatId, event.ID, event.Type, payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Send delivers event to webhook right away, without retries.
+func (d *Dispatcher) Send(ctx context.Context, webhook models.Webhook, event models.Event) error {
+	const op = "worker.webhook.Send"
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = d.post(ctx, webhook.Url, webhook.Secret, event.ID, event.Type, payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Run makes one delivery pass and returns how many deliveries succeeded.
+func (d *Dispatcher) Run(ctx context.Context) (int, error) {
+	const op = "worker.webhook.Run"
+
+	deliveries, err := d.storage.ClaimWebhookDeliveries(ctx, d.batchSize, d.lease)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	delivered := 0
+	for _, delivery := range deliveries {
+		err = d.post(ctx, delivery.Url, delivery.Secret, delivery.EventId, delivery.EventType, delivery.Payload)
+		if err == nil {
+			err = d.storage.CompleteWebhookDelivery(ctx, delivery.ID)
+			if err != nil {
+				return delivered, fmt.Errorf("%s: %w", op, err)
+			}
+			delivered++
+			continue
+		}
+
+		logger.GetFromCtx(ctx).Error(
+			ctx,
+			"failed to deliver webhook",
+			zap.Int64("id", delivery.ID),
+			zap.String("webhook_id", delivery.WebhookId),
+			zap.Int("attempts", delivery.Attempts),
+			zap.Error(err),
+		)
+
+		if delivery.Attempts+1 >= d.maxAttempts {
+			err = d.storage.DeadLetterWebhookDelivery(ctx, delivery.ID, err.Error())
+		} else {
+			retryAt := time.Now().Add(d.backoff(delivery.Attempts))
+			err = d.storage.FailWebhookDelivery(ctx, delivery.ID, err.Error(), retryAt)
+		}
+		if err != nil {
+			return delivered, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return delivered, nil
+}
+
+// Start delivers webhooks every interval until ctx is done.
+func (d *Dispatcher) Start(ctx context.Context, interval time.Duration) {
+	const op = "worker.webhook.Start"
+
+	ctx = logger.GetFromCtx(ctx).With(ctx, zap.String("op", op))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := d.Run(ctx)
+			if err != nil {
+				logger.GetFromCtx(ctx).Error(ctx, "failed to deliver webhooks", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Sign returns signature of payload sent at timestamp.
+func Sign(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) post(ctx context.Context, url, secret, eventId, eventType string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventId, eventId)
+	req.Header.Set(HeaderEventType, eventType)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// drain body, so connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := time.Second << min(attempts, 30)
+	if backoff > d.maxBackoff {
+		return d.maxBackoff
+	}
+
+	return backoff
+}
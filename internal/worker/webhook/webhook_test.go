@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/internal/models"
+	"github.com/AlexMickh/speak-chat/pkg/logger"
+)
+
+type fakeStorage struct {
+	deliveries   []models.WebhookDelivery
+	completed    []int64
+	failed       []int64
+	deadLettered []int64
+}
+
+func (f *fakeStorage) EnqueueWebhookDeliveries(ctx context.Context, chatId, eventId, eventType string, payload []byte) error {
+	return nil
+}
+
+func (f *fakeStorage) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	return f.deliveries, nil
+}
+
+func (f *fakeStorage) CompleteWebhookDelivery(ctx context.Context, id int64) error {
+	f.completed = append(f.completed, id)
+	return nil
+}
+
+func (f *fakeStorage) FailWebhookDelivery(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	f.failed = append(f.failed, id)
+	return nil
+}
+
+func (f *fakeStorage) DeadLetterWebhookDelivery(ctx context.Context, id int64, reason string) error {
+	f.deadLettered = append(f.deadLettered, id)
+	return nil
+}
+
+func TestDispatcher_Run(t *testing.T) {
+	ctx := logger.New(context.Background(), []string{"stderr"}, "prod")
+
+	const secret = "secret"
+	payload := []byte(`{"id":"1","type":"chat.updated"}`)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		want := Sign(secret, r.Header.Get(HeaderTimestamp), body)
+		if r.Header.Get(HeaderSignature) != want || r.Header.Get(HeaderEventId) != "1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	redirect := httptest.NewServer(http.RedirectHandler(receiver.URL, http.StatusFound))
+	defer redirect.Close()
+
+	tests := []struct {
+		name             string
+		delivery         models.WebhookDelivery
+		wantCompleted    bool
+		wantFailed       bool
+		wantDeadLettered bool
+	}{
+		{
+			name:          "signed delivery is accepted",
+			delivery:      models.WebhookDelivery{ID: 1, Url: receiver.URL, Secret: secret, EventId: "1"},
+			wantCompleted: true,
+		},
+		{
+			name:       "wrong secret is retried",
+			delivery:   models.WebhookDelivery{ID: 1, Url: receiver.URL, Secret: "other", EventId: "1"},
+			wantFailed: true,
+		},
+		{
+			name:       "server error is retried",
+			delivery:   models.WebhookDelivery{ID: 1, Url: broken.URL, Secret: secret, EventId: "1", Attempts: 1},
+			wantFailed: true,
+		},
+		{
+			name:       "redirect is not followed",
+			delivery:   models.WebhookDelivery{ID: 1, Url: redirect.URL, Secret: secret, EventId: "1"},
+			wantFailed: true,
+		},
+		{
+			name:             "last attempt goes to dead letters",
+			delivery:         models.WebhookDelivery{ID: 1, Url: broken.URL, Secret: secret, EventId: "1", Attempts: 2},
+			wantDeadLettered: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.delivery.Payload = payload
+			store := &fakeStorage{deliveries: []models.WebhookDelivery{tt.delivery}}
+			// test servers listen on loopback, so address check is off
+			d := New(store, newClient(time.Second, nil), 10, time.Minute, 3, time.Minute)
+
+			_, err := d.Run(ctx)
+			if err != nil {
+				t.Fatalf("Dispatcher.Run() error = %v", err)
+			}
+
+			if got := len(store.completed) == 1; got != tt.wantCompleted {
+				t.Errorf("delivery completed = %v, want %v", got, tt.wantCompleted)
+			}
+			if got := len(store.failed) == 1; got != tt.wantFailed {
+				t.Errorf("delivery failed = %v, want %v", got, tt.wantFailed)
+			}
+			if got := len(store.deadLettered) == 1; got != tt.wantDeadLettered {
+				t.Errorf("delivery dead lettered = %v, want %v", got, tt.wantDeadLettered)
+			}
+		})
+	}
+}
+
+func TestDispatcher_SendRefusesInternalAddress(t *testing.T) {
+	received := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	d := New(&fakeStorage{}, NewClient(time.Second), 10, time.Minute, 3, time.Minute)
+
+	err := d.Send(
+		context.Background(),
+		models.Webhook{Url: receiver.URL, Secret: "secret"},
+		models.Event{ID: "1", Type: models.EventWebhookTest},
+	)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Dispatcher.Send() error = %v, want %v", err, ErrForbiddenAddress)
+	}
+	if received {
+		t.Error("delivery reached loopback receiver")
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		wantErr error
+	}{
+		{
+			name:    "public ipv4",
+			address: "93.184.216.34:443",
+		},
+		{
+			name:    "public ipv6",
+			address: "[2606:2800:220:1::1]:443",
+		},
+		{
+			name:    "loopback",
+			address: "127.0.0.1:80",
+			wantErr: ErrForbiddenAddress,
+		},
+		{
+			name:    "ipv6 loopback",
+			address: "[::1]:80",
+			wantErr: ErrForbiddenAddress,
+		},
+		{
+			name:    "ipv4 mapped loopback",
+			address: "[::ffff:127.0.0.1]:80",
+			wantErr: ErrForbiddenAddress,
+		},
+		{
+			name:    "private",
+			address: "10.0.0.5:80",
+			wantErr: ErrForbiddenAddress,
+		},
+		{
+			name:    "link-local metadata",
+			address: "169.254.169.254:80",
+			wantErr: ErrForbiddenAddress,
+		},
+		{
+			name:    "unspecified",
+			address: "0.0.0.0:80",
+			wantErr: ErrForbiddenAddress,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAddress("tcp", tt.address, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkAddress() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS chat.invites(
+    code TEXT PRIMARY KEY,
+    chat_id UUID NOT NULL,
+    created_by TEXT NOT NULL,
+    -- NULL never expires, zero max uses is unlimited
+    expires_at TIMESTAMPTZ,
+    max_uses INT NOT NULL DEFAULT 0,
+    uses INT NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS invites_chat_id_idx ON chat.invites (chat_id);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS chat.chat_join_requests(
+    id UUID PRIMARY KEY,
+    chat_id UUID NOT NULL,
+    user_id TEXT NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending',
+    decided_by TEXT,
+    decided_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- user can have only one pending request per chat
+CREATE UNIQUE INDEX IF NOT EXISTS chat_join_requests_pending_idx
+ON chat.chat_join_requests (chat_id, user_id) WHERE status = 'pending';
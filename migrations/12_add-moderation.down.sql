@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS chat.mutes;
+DROP TABLE IF EXISTS chat.bans;
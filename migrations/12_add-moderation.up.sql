@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS chat.bans(
+    chat_id UUID NOT NULL,
+    user_id TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    banned_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS chat.mutes(
+    chat_id UUID NOT NULL,
+    user_id TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    muted_by TEXT NOT NULL,
+    until TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (chat_id, user_id)
+);
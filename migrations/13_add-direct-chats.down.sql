@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS chat.chats_direct_pair_idx;
+
+ALTER TABLE chat.chats
+DROP COLUMN IF EXISTS type;
@@ -0,0 +1,7 @@
+ALTER TABLE chat.chats
+ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'group';
+
+-- one direct chat per unordered pair of users
+CREATE UNIQUE INDEX IF NOT EXISTS chats_direct_pair_idx
+ON chat.chats (LEAST(participants_id[1], participants_id[2]), GREATEST(participants_id[1], participants_id[2]))
+WHERE type = 'direct' AND deleted_at IS NULL;
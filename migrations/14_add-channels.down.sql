@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS chat.channel_subscribers;
+
+DROP INDEX IF EXISTS chat.chats_direct_pair_idx;
+
+ALTER TABLE chat.chats
+DROP COLUMN IF EXISTS subscribers_count,
+ALTER COLUMN type DROP DEFAULT,
+ALTER COLUMN type TYPE TEXT USING type::text,
+ALTER COLUMN type SET DEFAULT 'group';
+
+DROP TYPE IF EXISTS chat.chat_type;
+
+CREATE UNIQUE INDEX IF NOT EXISTS chats_direct_pair_idx
+ON chat.chats (LEAST(participants_id[1], participants_id[2]), GREATEST(participants_id[1], participants_id[2]))
+WHERE type = 'direct' AND deleted_at IS NULL;
@@ -0,0 +1,23 @@
+DROP INDEX IF EXISTS chat.chats_direct_pair_idx;
+
+CREATE TYPE chat.chat_type AS ENUM ('group', 'direct', 'channel');
+
+ALTER TABLE chat.chats
+ALTER COLUMN type DROP DEFAULT,
+ALTER COLUMN type TYPE chat.chat_type USING type::chat.chat_type,
+ALTER COLUMN type SET DEFAULT 'group',
+ADD COLUMN IF NOT EXISTS subscribers_count BIGINT NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX IF NOT EXISTS chats_direct_pair_idx
+ON chat.chats (LEAST(participants_id[1], participants_id[2]), GREATEST(participants_id[1], participants_id[2]))
+WHERE type = 'direct' AND deleted_at IS NULL;
+
+-- channel participants_id holds admins only, subscribers live here
+CREATE TABLE IF NOT EXISTS chat.channel_subscribers(
+    chat_id UUID NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS channel_subscribers_user_id_idx ON chat.channel_subscribers (user_id);
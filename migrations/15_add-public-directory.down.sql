@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS chat.chats_search_vector_idx;
+
+ALTER TABLE chat.chats
+DROP COLUMN IF EXISTS search_vector,
+DROP COLUMN IF EXISTS is_public;
@@ -0,0 +1,10 @@
+ALTER TABLE chat.chats
+ADD COLUMN IF NOT EXISTS is_public BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS chats_search_vector_idx
+ON chat.chats USING GIN (search_vector)
+WHERE is_public AND deleted_at IS NULL;
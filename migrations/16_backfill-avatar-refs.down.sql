@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS chat.chat_avatar_versions_avatar_hash_idx;
+
+-- backfilled references can not be told apart from the ones taken later,
+-- so they are kept
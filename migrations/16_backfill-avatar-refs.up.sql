@@ -0,0 +1,13 @@
+-- avatars uploaded before content addressing have no row, so their old
+-- versions looked unused to gc. Every version owns one reference.
+INSERT INTO chat.avatars (hash, size, ref_count)
+SELECT avatar_hash, 0, count(*)
+FROM chat.chat_avatar_versions
+WHERE avatar_hash <> ''
+GROUP BY avatar_hash
+ON CONFLICT (hash) DO UPDATE
+SET ref_count = GREATEST(chat.avatars.ref_count, EXCLUDED.ref_count),
+    updated_at = CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS chat_avatar_versions_avatar_hash_idx
+ON chat.chat_avatar_versions (avatar_hash);
@@ -0,0 +1,3 @@
+ALTER TABLE chat.chats DROP COLUMN avatar_hash;
+
+DROP TABLE IF EXISTS chat.avatars;
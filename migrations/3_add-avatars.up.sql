@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS chat.avatars(
+    hash TEXT PRIMARY KEY,
+    size BIGINT NOT NULL,
+    ref_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE chat.chats
+ADD COLUMN avatar_hash TEXT NOT NULL DEFAULT '';
+
+-- presigned objects uploaded before content addressing are keyed by chat id,
+-- public ones are keyed by the last segment of their url
+UPDATE chat.chats
+SET avatar_hash = CASE
+    WHEN chat_image_url LIKE '%?%' THEN id::text
+    ELSE regexp_replace(chat_image_url, '^.*/', '')
+END
+WHERE chat_image_url NOT LIKE '%/avatar.png%';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS chat.chat_avatar_versions;
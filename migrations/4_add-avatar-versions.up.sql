@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS chat.chat_avatar_versions(
+    id BIGSERIAL PRIMARY KEY,
+    chat_id UUID NOT NULL,
+    avatar_hash TEXT NOT NULL,
+    created_by TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS chat_avatar_versions_chat_id_idx
+ON chat.chat_avatar_versions (chat_id, created_at DESC);
+
+-- every version owns one avatar reference, references of versions created
+-- here are backfilled by 16_backfill-avatar-refs
+INSERT INTO chat.chat_avatar_versions (chat_id, avatar_hash, created_by, created_at)
+SELECT id, avatar_hash, owner_id, COALESCE(updated_at, created_at)
+FROM chat.chats
+WHERE avatar_hash <> '';
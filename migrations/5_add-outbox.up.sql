@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS chat.outbox(
+    id BIGSERIAL PRIMARY KEY,
+    chat_id UUID NOT NULL,
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS outbox_available_at_idx ON chat.outbox (available_at, id);
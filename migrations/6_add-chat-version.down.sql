@@ -0,0 +1 @@
+ALTER TABLE chat.chats DROP COLUMN IF EXISTS version;
@@ -0,0 +1,2 @@
+-- version grows on every change made by users and is exposed as etag
+ALTER TABLE chat.chats ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS chat.chats_deleted_at_idx;
+
+ALTER TABLE chat.chats DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,6 @@
+ALTER TABLE chat.chats ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- purge worker scans only deleted chats
+CREATE INDEX IF NOT EXISTS chats_deleted_at_idx
+ON chat.chats (deleted_at)
+WHERE deleted_at IS NOT NULL;
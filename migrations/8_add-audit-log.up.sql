@@ -0,0 +1,18 @@
+-- append-only, partitioned by month; partitions are created ahead by
+-- audit partition worker, default one catches rows nobody prepared for
+CREATE TABLE IF NOT EXISTS chat.audit_log(
+    id BIGSERIAL,
+    chat_id UUID NOT NULL,
+    actor_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    diff JSONB NOT NULL DEFAULT '{}',
+    request_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE TABLE IF NOT EXISTS chat.audit_log_default
+PARTITION OF chat.audit_log DEFAULT;
+
+CREATE INDEX IF NOT EXISTS audit_log_chat_id_idx
+ON chat.audit_log (chat_id, id DESC);
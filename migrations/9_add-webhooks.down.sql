@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS chat.webhook_dead_letters;
+DROP TABLE IF EXISTS chat.webhook_deliveries;
+DROP TABLE IF EXISTS chat.webhooks;
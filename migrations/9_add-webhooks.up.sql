@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS chat.webhooks(
+    id UUID PRIMARY KEY,
+    chat_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_chat_id_idx ON chat.webhooks (chat_id);
+
+-- one row per webhook and event, pending or being retried
+CREATE TABLE IF NOT EXISTS chat.webhook_deliveries(
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id UUID NOT NULL REFERENCES chat.webhooks (id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_available_at_idx
+ON chat.webhook_deliveries (available_at, id);
+
+-- deliveries that ran out of attempts, kept for inspection and replay
+CREATE TABLE IF NOT EXISTS chat.webhook_dead_letters(
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id UUID NOT NULL,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL,
+    last_error TEXT,
+    failed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
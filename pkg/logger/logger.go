@@ -111,10 +111,9 @@ func Interceptor(ctx context.Context) grpc.UnaryServerInterceptor {
 
 		md, ok := metadata.FromIncomingContext(lCtx)
 		if ok {
-			guid, ok := md[RequestID]
-			if ok {
-				GetFromCtx(lCtx).Error(ctx, "No request id")
-				ctx = context.WithValue(ctx, RequestID, guid)
+			guid := md.Get(RequestID)
+			if len(guid) > 0 {
+				lCtx = context.WithValue(lCtx, RequestID, guid[0])
 			}
 		}
 
@@ -0,0 +1,90 @@
+package natsclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AlexMickh/speak-chat/pkg/utils/retry"
+	"github.com/nats-io/nats.go"
+)
+
+type NatsConfig struct {
+	addr      string
+	user      string
+	password  string
+	token     string
+	credsFile string
+	tlsCA     string
+	tlsCert   string
+	tlsKey    string
+	timeout   time.Duration
+}
+
+func NewConfig(
+	addr string,
+	user string,
+	password string,
+	token string,
+	credsFile string,
+	tlsCA string,
+	tlsCert string,
+	tlsKey string,
+	timeout time.Duration,
+) *NatsConfig {
+	return &NatsConfig{
+		addr:      addr,
+		user:      user,
+		password:  password,
+		token:     token,
+		credsFile: credsFile,
+		tlsCA:     tlsCA,
+		tlsCert:   tlsCert,
+		tlsKey:    tlsKey,
+		timeout:   timeout,
+	}
+}
+
+// New connects to nats server. Connection reconnects on its own
+// after it was established.
+func New(cfg *NatsConfig) (*nats.Conn, error) {
+	const op = "nats-client.New"
+
+	opts := []nats.Option{
+		nats.Name("speak-chat"),
+		nats.Timeout(cfg.timeout),
+		nats.MaxReconnects(-1),
+	}
+	if cfg.user != "" {
+		opts = append(opts, nats.UserInfo(cfg.user, cfg.password))
+	}
+	if cfg.token != "" {
+		opts = append(opts, nats.Token(cfg.token))
+	}
+	if cfg.credsFile != "" {
+		opts = append(opts, nats.UserCredentials(cfg.credsFile))
+	}
+	if cfg.tlsCA != "" {
+		opts = append(opts, nats.RootCAs(cfg.tlsCA))
+	}
+	if cfg.tlsCert != "" {
+		opts = append(opts, nats.ClientCert(cfg.tlsCert, cfg.tlsKey))
+	}
+
+	var nc *nats.Conn
+
+	err := retry.WithDelay(5, 500*time.Millisecond, func() error {
+		var err error
+
+		nc, err = nats.Connect(cfg.addr, opts...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nc, nil
+}